@@ -1,12 +1,11 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"strings"
-
-	"github.com/scottdware/go-rested"
 )
 
 // AddressObjects contains a slice of all address objects.
@@ -17,13 +16,30 @@ type AddressObjects struct {
 	Addresses []Address `xml:"result>address>entry"`
 }
 
-// Address contains information about each individual address object.
+// Address contains information about each individual address object. Only one of IPAddress, IPRange, IPWildcard
+// or FQDN is set, depending on the type of address. DisableOverride is only used on Panorama, and is one of: yes or no.
 type Address struct {
-	Name        string `xml:"name,attr"`
-	IPAddress   string `xml:"ip-netmask,omitempty"`
-	IPRange     string `xml:"ip-range,omitempty"`
-	FQDN        string `xml:"fqdn,omitempty"`
-	Description string `xml:"description,omitempty"`
+	Name            string   `xml:"name,attr"`
+	IPAddress       string   `xml:"ip-netmask,omitempty"`
+	IPRange         string   `xml:"ip-range,omitempty"`
+	IPWildcard      string   `xml:"ip-wildcard,omitempty"`
+	FQDN            string   `xml:"fqdn,omitempty"`
+	Description     string   `xml:"description,omitempty"`
+	Tags            []string `xml:"tag>member,omitempty"`
+	DisableOverride string   `xml:"disable-override,omitempty"`
+}
+
+// xmlAddress is used for creating or editing an address object.
+type xmlAddress struct {
+	XMLName         xml.Name    `xml:"entry"`
+	Name            string      `xml:"name,attr"`
+	IPAddress       string      `xml:"ip-netmask,omitempty"`
+	IPRange         string      `xml:"ip-range,omitempty"`
+	IPWildcard      string      `xml:"ip-wildcard,omitempty"`
+	FQDN            string      `xml:"fqdn,omitempty"`
+	Description     string      `xml:"description,omitempty"`
+	Tags            *xmlMembers `xml:"tag,omitempty"`
+	DisableOverride string      `xml:"disable-override,omitempty"`
 }
 
 // AddressGroups contains a slice of all address groups.
@@ -56,27 +72,73 @@ type xmlAddressGroup struct {
 	Description   string   `xml:"description,omitempty"`
 }
 
-// Addresses returns information about all of the address objects. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all objects are returned.
-func (p *PaloAlto) Addresses(devicegroup ...string) (*AddressObjects, error) {
-	var addrs AddressObjects
-	xpath := "/config/devices/entry//address"
-	r := rested.NewRequest()
+// Type returns the type of the address object, which is one of: ip, range, wildcard or fqdn. These are the same
+// types used when creating an address object.
+func (a *Address) Type() string {
+	switch {
+	case a.IPAddress != "":
+		return "ip"
+	case a.IPRange != "":
+		return "range"
+	case a.IPWildcard != "":
+		return "wildcard"
+	case a.FQDN != "":
+		return "fqdn"
+	}
+
+	return ""
+}
+
+// toXML converts the address object into the form used when creating or editing it, and makes sure that
+// exactly one type of address is set.
+func (a *Address) toXML() (*xmlAddress, error) {
+	set := 0
 
-	if p.DeviceType != "panorama" && len(devicegroup) > 0 {
-		return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
+	for _, v := range []string{a.IPAddress, a.IPRange, a.IPWildcard, a.FQDN} {
+		if v != "" {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("address object %s must have exactly one of: ip-netmask, ip-range, ip-wildcard or fqdn", a.Name)
 	}
 
+	return &xmlAddress{
+		Name:            a.Name,
+		IPAddress:       a.IPAddress,
+		IPRange:         a.IPRange,
+		IPWildcard:      a.IPWildcard,
+		FQDN:            a.FQDN,
+		Description:     a.Description,
+		Tags:            newMembers(a.Tags...),
+		DisableOverride: a.DisableOverride,
+	}, nil
+}
+
+// Addresses returns information about all of the address objects. You can (optionally) specify a Location as the
+// last parameter, such as a device-group when ran against a Panorama device. If no location is specified, then all
+// objects are returned. When ran against a firewall, you can (optionally) specify a VsysLocation instead.
+func (p *PaloAlto) Addresses(loc ...Location) (*AddressObjects, error) {
+	return p.AddressesContext(context.Background(), loc...)
+}
+
+// AddressesContext is the same as Addresses, but uses the given context for all API requests.
+func (p *PaloAlto) AddressesContext(ctx context.Context, loc ...Location) (*AddressObjects, error) {
+	var addrs AddressObjects
+	xpath := "/config/devices/entry//address"
+
 	if p.DeviceType == "panos" && p.Panorama == true {
 		xpath = "/config/panorama//address"
 	}
 
-	if p.DeviceType == "panos" && p.Panorama == false {
-		xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address"
-	}
+	if (p.DeviceType == "panos" && p.Panorama == false) || len(loc) > 0 {
+		base, err := p.locationXpath(loc)
+		if err != nil {
+			return nil, err
+		}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address", devicegroup[0])
+		xpath = base + "/address"
 	}
 
 	query := map[string]string{
@@ -85,41 +147,46 @@ func (p *PaloAlto) Addresses(devicegroup ...string) (*AddressObjects, error) {
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	addrData := r.Send("get", p.URI, nil, headers, query)
+	addrData := p.send(ctx, "get", query)
+	if addrData.Error != nil {
+		return nil, addrData.Error
+	}
 
 	if err := xml.Unmarshal(addrData.Body, &addrs); err != nil {
 		return nil, err
 	}
 
 	if addrs.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", addrs.Code, errorCodes[addrs.Code])
+		return nil, newAPIError(addrData, query)
 	}
 
 	return &addrs, nil
 }
 
-// AddressGroups returns information about all of the address groups. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all address groups are returned.
-func (p *PaloAlto) AddressGroups(devicegroup ...string) (*AddressGroups, error) {
+// AddressGroups returns information about all of the address groups. You can (optionally) specify a Location as the
+// last parameter, such as a device-group when ran against a Panorama device. If no location is specified, then all
+// address groups are returned. When ran against a firewall, you can (optionally) specify a VsysLocation instead.
+func (p *PaloAlto) AddressGroups(loc ...Location) (*AddressGroups, error) {
+	return p.AddressGroupsContext(context.Background(), loc...)
+}
+
+// AddressGroupsContext is the same as AddressGroups, but uses the given context for all API requests.
+func (p *PaloAlto) AddressGroupsContext(ctx context.Context, loc ...Location) (*AddressGroups, error) {
 	var parsedGroups xmlAddressGroups
 	var groups AddressGroups
 	xpath := "/config/devices/entry//address-group"
-	r := rested.NewRequest()
-
-	if p.DeviceType != "panorama" && len(devicegroup) > 0 {
-		return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-	}
 
 	if p.DeviceType == "panos" && p.Panorama == true {
 		xpath = "/config/panorama//address-group"
 	}
 
-	if p.DeviceType == "panos" && p.Panorama == false {
-		xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group"
-	}
+	if (p.DeviceType == "panos" && p.Panorama == false) || len(loc) > 0 {
+		base, err := p.locationXpath(loc)
+		if err != nil {
+			return nil, err
+		}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group", devicegroup[0])
+		xpath = base + "/address-group"
 	}
 
 	query := map[string]string{
@@ -128,14 +195,17 @@ func (p *PaloAlto) AddressGroups(devicegroup ...string) (*AddressGroups, error)
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	groupData := r.Send("get", p.URI, nil, headers, query)
+	groupData := p.send(ctx, "get", query)
+	if groupData.Error != nil {
+		return nil, groupData.Error
+	}
 
 	if err := xml.Unmarshal(groupData.Body, &parsedGroups); err != nil {
 		return nil, err
 	}
 
 	if parsedGroups.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", parsedGroups.Code, errorCodes[parsedGroups.Code])
+		return nil, newAPIError(groupData, query)
 	}
 
 	for _, g := range parsedGroups.Groups {
@@ -155,38 +225,46 @@ func (p *PaloAlto) AddressGroups(devicegroup ...string) (*AddressGroups, error)
 	return &groups, nil
 }
 
-// CreateAddress will add a new address object to the device. addrtype should be one of: ip, range, or fqdn. If creating
-// an address object on a Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) CreateAddress(name, addrtype, address, description string, devicegroup ...string) error {
+// CreateAddress will add a new address object to the device. addrtype should be one of: ip, range, wildcard, or
+// fqdn. If creating an address object on a Panorama device, then specify its location (i.e. a DeviceGroupLocation)
+// as the last parameter. On a multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) CreateAddress(name, addrtype, address, description string, loc ...Location) error {
+	return p.CreateAddressContext(context.Background(), name, addrtype, address, description, loc...)
+}
+
+// CreateAddressContext is the same as CreateAddress, but uses the given context for all API requests.
+func (p *PaloAlto) CreateAddressContext(ctx context.Context, name, addrtype, address, description string, loc ...Location) error {
 	var xmlBody string
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
+
+	if err := validateName(name); err != nil {
+		return err
+	}
 
 	switch addrtype {
 	case "ip":
-		xmlBody = fmt.Sprintf("<ip-netmask>%s</ip-netmask>", address)
+		xmlBody = fmt.Sprintf("<ip-netmask>%s</ip-netmask>", escapeXML(address))
 	case "range":
-		xmlBody = fmt.Sprintf("<ip-range>%s</ip-range>", address)
+		xmlBody = fmt.Sprintf("<ip-range>%s</ip-range>", escapeXML(address))
+	case "wildcard":
+		xmlBody = fmt.Sprintf("<ip-wildcard>%s</ip-wildcard>", escapeXML(address))
 	case "fqdn":
-		xmlBody = fmt.Sprintf("<fqdn>%s</fqdn>", address)
+		xmlBody = fmt.Sprintf("<fqdn>%s</fqdn>", escapeXML(address))
+	default:
+		return fmt.Errorf("unknown address type %s, should be one of: ip, range, wildcard or fqdn", addrtype)
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']", name)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/address/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":    "config",
@@ -196,7 +274,7 @@ func (p *PaloAlto) CreateAddress(name, addrtype, address, description string, de
 		"key":     p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -206,99 +284,97 @@ func (p *PaloAlto) CreateAddress(name, addrtype, address, description string, de
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
-// CreateSharedAddress will add a new shared address object to Panorama. addrtype should be one of: ip, range, or fqdn.
-func (p *PaloAlto) CreateSharedAddress(name, addrtype, address, description string) error {
-	var xmlBody string
+// EditAddress replaces an existing address object with the given one, matching it by name. Since the object is
+// updated in place, any groups or rules that reference it are left intact. The entire object is replaced, so include
+// any tags you want to keep - editing an object returned by Addresses keeps everything as-is. If editing an address
+// object on a Panorama device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a
+// multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) EditAddress(addr *Address, loc ...Location) error {
+	return p.EditAddressContext(context.Background(), addr, loc...)
+}
+
+// EditAddressContext is the same as EditAddress, but uses the given context for all API requests.
+func (p *PaloAlto) EditAddressContext(ctx context.Context, addr *Address, loc ...Location) error {
 	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
 
-	switch addrtype {
-	case "ip":
-		xmlBody = fmt.Sprintf("<ip-netmask>%s</ip-netmask>", address)
-	case "range":
-		xmlBody = fmt.Sprintf("<ip-range>%s</ip-range>", address)
-	case "fqdn":
-		xmlBody = fmt.Sprintf("<fqdn>%s</fqdn>", address)
+	if addr == nil || addr.Name == "" {
+		return errors.New("you must specify the name of the address object to edit")
 	}
 
-	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+	x, err := addr.toXML()
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panos" {
-		return errors.New("you can only create shared objects when connected to a Panorama device")
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']", name)
-	}
+	xpath = base + "/address"
 
-	query := map[string]string{
-		"type":    "config",
-		"action":  "set",
-		"xpath":   xpath,
-		"element": xmlBody,
-		"key":     p.Key,
-	}
+	return p.setEntry(ctx, "edit", xpath, addr.Name, x)
+}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
+// CreateSharedAddress will add a new shared address object to Panorama. addrtype should be one of: ip, range, wildcard, or fqdn.
+func (p *PaloAlto) CreateSharedAddress(name, addrtype, address, description string) error {
+	return p.CreateSharedAddressContext(context.Background(), name, addrtype, address, description)
+}
 
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
+// CreateSharedAddressContext is the same as CreateSharedAddress, but uses the given context for all API requests.
+func (p *PaloAlto) CreateSharedAddressContext(ctx context.Context, name, addrtype, address, description string) error {
+	if p.DeviceType == "panos" {
+		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
+	return p.CreateAddressContext(ctx, name, addrtype, address, description, SharedLocation())
+}
 
-	return nil
+// CreateStaticGroup will create a new static address group on the device. You can specify multiple members by
+// separating them with a comma, i.e. "web-server1, web-server2". If creating an address group on a Panorama device,
+// then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can
+// specify a VsysLocation instead.
+func (p *PaloAlto) CreateStaticGroup(name, members, description string, loc ...Location) error {
+	return p.CreateStaticGroupContext(context.Background(), name, members, description, loc...)
 }
 
-// CreateStaticGroup will create a new static address group on the device. You can specify multiple members
-// by separating them with a comma, i.e. "web-server1, web-server2". If creating an address group on
-// a Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) CreateStaticGroup(name, members, description string, devicegroup ...string) error {
+// CreateStaticGroupContext is the same as CreateStaticGroup, but uses the given context for all API requests.
+func (p *PaloAlto) CreateStaticGroupContext(ctx context.Context, name, members, description string, loc ...Location) error {
 	var xmlBody string
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 	m := strings.Split(members, ",")
 
+	if err := validateName(name); err != nil {
+		return err
+	}
+
 	if members == "" {
 		return errors.New("you cannot create a static address group without any members")
 	}
 
 	xmlBody = "<static>"
 	for _, member := range m {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(member))
+		xmlBody += fmt.Sprintf("<member>%s</member>", escapeXML(strings.TrimSpace(member)))
 	}
 	xmlBody += "</static>"
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']", name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']", devicegroup[0], name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/address-group/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":    "config",
@@ -308,7 +384,7 @@ func (p *PaloAlto) CreateStaticGroup(name, members, description string, devicegr
 		"key":     p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -318,7 +394,7 @@ func (p *PaloAlto) CreateStaticGroup(name, members, description string, devicegr
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -327,86 +403,50 @@ func (p *PaloAlto) CreateStaticGroup(name, members, description string, devicegr
 // CreateSharedStaticGroup will create a new shared static address group on Panorama. You can specify multiple members
 // by separating them with a comma, i.e. "web-server1, web-server2".
 func (p *PaloAlto) CreateSharedStaticGroup(name, members, description string) error {
-	var xmlBody string
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
-	m := strings.Split(members, ",")
-
-	if members == "" {
-		return errors.New("you cannot create a static address group without any members")
-	}
-
-	xmlBody = "<static>"
-	for _, member := range m {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(member))
-	}
-	xmlBody += "</static>"
-
-	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
+	return p.CreateSharedStaticGroupContext(context.Background(), name, members, description)
+}
 
+// CreateSharedStaticGroupContext is the same as CreateSharedStaticGroup, but uses the given context for all API requests.
+func (p *PaloAlto) CreateSharedStaticGroupContext(ctx context.Context, name, members, description string) error {
 	if p.DeviceType == "panos" {
 		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", name)
-	}
-
-	query := map[string]string{
-		"type":    "config",
-		"action":  "set",
-		"xpath":   xpath,
-		"element": xmlBody,
-		"key":     p.Key,
-	}
-
-	resp := r.Send("post", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
-	return nil
+	return p.CreateStaticGroupContext(ctx, name, members, description, SharedLocation())
 }
 
 // CreateDynamicGroup will create a new dynamic address group on the device. The filter must be written like so:
 // 'vm-servers' and 'some tag' or 'pcs' - using the tags as the match criteria. If creating an address group on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) CreateDynamicGroup(name, criteria, description string, devicegroup ...string) error {
-	xmlBody := fmt.Sprintf("<dynamic><filter>%s</filter></dynamic>", criteria)
+// Panorama device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys
+// firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) CreateDynamicGroup(name, criteria, description string, loc ...Location) error {
+	return p.CreateDynamicGroupContext(context.Background(), name, criteria, description, loc...)
+}
+
+// CreateDynamicGroupContext is the same as CreateDynamicGroup, but uses the given context for all API requests.
+func (p *PaloAlto) CreateDynamicGroupContext(ctx context.Context, name, criteria, description string, loc ...Location) error {
+	xmlBody := fmt.Sprintf("<dynamic><filter>%s</filter></dynamic>", escapeXML(criteria))
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
+
+	if err := validateName(name); err != nil {
+		return err
+	}
 
 	if criteria == "" {
 		return errors.New("you cannot create a dynamic address group without any filter")
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']", name)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/address-group/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":    "config",
@@ -416,7 +456,7 @@ func (p *PaloAlto) CreateDynamicGroup(name, criteria, description string, device
 		"key":     p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -426,7 +466,7 @@ func (p *PaloAlto) CreateDynamicGroup(name, criteria, description string, device
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -435,69 +475,36 @@ func (p *PaloAlto) CreateDynamicGroup(name, criteria, description string, device
 // CreateSharedDynamicGroup will create a new shared dynamic address group on Panorama. The filter must be written like so:
 // 'vm-servers' and 'some tag' or 'pcs' - using the tags as the match criteria.
 func (p *PaloAlto) CreateSharedDynamicGroup(name, criteria, description string) error {
-	xmlBody := fmt.Sprintf("<dynamic><filter>%s</filter></dynamic>", criteria)
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
-
-	if criteria == "" {
-		return errors.New("you cannot create a dynamic address group without any filter")
-	}
-
-	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
+	return p.CreateSharedDynamicGroupContext(context.Background(), name, criteria, description)
+}
 
+// CreateSharedDynamicGroupContext is the same as CreateSharedDynamicGroup, but uses the given context for all API requests.
+func (p *PaloAlto) CreateSharedDynamicGroupContext(ctx context.Context, name, criteria, description string) error {
 	if p.DeviceType == "panos" {
 		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", name)
-	}
-
-	query := map[string]string{
-		"type":    "config",
-		"action":  "set",
-		"xpath":   xpath,
-		"element": xmlBody,
-		"key":     p.Key,
-	}
-
-	resp := r.Send("post", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
+	return p.CreateDynamicGroupContext(ctx, name, criteria, description, SharedLocation())
+}
 
-	return nil
+// DeleteAddress will remove an address object from the device. If deleting an address object on a Panorama device,
+// then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can
+// specify a VsysLocation instead.
+func (p *PaloAlto) DeleteAddress(name string, loc ...Location) error {
+	return p.DeleteAddressContext(context.Background(), name, loc...)
 }
 
-// DeleteAddress will remove an address object from the device. If deleting an address object on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) DeleteAddress(name string, devicegroup ...string) error {
+// DeleteAddressContext is the same as DeleteAddress, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteAddressContext(ctx context.Context, name string, loc ...Location) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']", name)
-	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/address/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":   "config",
@@ -506,7 +513,7 @@ func (p *PaloAlto) DeleteAddress(name string, devicegroup ...string) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -516,7 +523,7 @@ func (p *PaloAlto) DeleteAddress(name string, devicegroup ...string) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -524,59 +531,36 @@ func (p *PaloAlto) DeleteAddress(name string, devicegroup ...string) error {
 
 // DeleteSharedAddress will remove a shared address object from Panorama.
 func (p *PaloAlto) DeleteSharedAddress(name string) error {
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
+	return p.DeleteSharedAddressContext(context.Background(), name)
+}
 
+// DeleteSharedAddressContext is the same as DeleteSharedAddress, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteSharedAddressContext(ctx context.Context, name string) error {
 	if p.DeviceType == "panos" {
 		return errors.New("you can only remove shared objects when connected to a Panorama device")
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/address/entry[@name='%s']", name)
-	}
-
-	query := map[string]string{
-		"type":   "config",
-		"action": "delete",
-		"xpath":  xpath,
-		"key":    p.Key,
-	}
-
-	resp := r.Send("get", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
+	return p.DeleteAddressContext(ctx, name, SharedLocation())
+}
 
-	return nil
+// DeleteAddressGroup will remove an address group from the device. If deleting an address group on a Panorama
+// device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall,
+// you can specify a VsysLocation instead.
+func (p *PaloAlto) DeleteAddressGroup(name string, loc ...Location) error {
+	return p.DeleteAddressGroupContext(context.Background(), name, loc...)
 }
 
-// DeleteAddressGroup will remove an address group from the device. If deleting an address group on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) DeleteAddressGroup(name string, devicegroup ...string) error {
+// DeleteAddressGroupContext is the same as DeleteAddressGroup, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteAddressGroupContext(ctx context.Context, name string, loc ...Location) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/address-group/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":   "config",
@@ -585,7 +569,7 @@ func (p *PaloAlto) DeleteAddressGroup(name string, devicegroup ...string) error
 		"key":    p.Key,
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -595,7 +579,7 @@ func (p *PaloAlto) DeleteAddressGroup(name string, devicegroup ...string) error
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -603,37 +587,14 @@ func (p *PaloAlto) DeleteAddressGroup(name string, devicegroup ...string) error
 
 // DeleteSharedAddressGroup will remove a shared address group from Panorama.
 func (p *PaloAlto) DeleteSharedAddressGroup(name string) error {
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
+	return p.DeleteSharedAddressGroupContext(context.Background(), name)
+}
 
+// DeleteSharedAddressGroupContext is the same as DeleteSharedAddressGroup, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteSharedAddressGroupContext(ctx context.Context, name string) error {
 	if p.DeviceType == "panos" {
 		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/address-group/entry[@name='%s']", name)
-	}
-
-	query := map[string]string{
-		"type":   "config",
-		"action": "delete",
-		"xpath":  xpath,
-		"key":    p.Key,
-	}
-
-	resp := r.Send("get", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
-	return nil
+	return p.DeleteAddressGroupContext(ctx, name, SharedLocation())
 }
@@ -0,0 +1,139 @@
+package panos
+
+import (
+	"encoding/xml"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestCreateAddress(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	tests := []struct {
+		addrtype string
+		element  string
+	}{
+		{"ip", "<ip-netmask>10.1.1.1/32</ip-netmask><description>web</description>"},
+		{"range", "<ip-range>10.1.1.1/32</ip-range><description>web</description>"},
+		{"wildcard", "<ip-wildcard>10.1.1.1/32</ip-wildcard><description>web</description>"},
+		{"fqdn", "<fqdn>10.1.1.1/32</fqdn><description>web</description>"},
+	}
+
+	for i, tt := range tests {
+		if err := p.CreateAddress("web", tt.addrtype, "10.1.1.1/32", "web"); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := d.sent("set")[i].Get("element"); got != tt.element {
+			t.Errorf("%s: got %s", tt.addrtype, got)
+		}
+	}
+}
+
+func TestCreateAddressUnknownType(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	if err := p.CreateAddress("web", "host", "10.1.1.1", ""); err == nil {
+		t.Error("expected an error for an unknown address type")
+	}
+
+	if len(d.sent("")) != 0 {
+		t.Error("an address with an unknown type should not be sent")
+	}
+}
+
+func TestAddressesParse(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result><address><entry name="web"><ip-netmask>10.1.1.1</ip-netmask>` +
+			`<tag><member>prod</member></tag><disable-override>no</disable-override></entry>` +
+			`<entry name="site"><fqdn>example.com</fqdn></entry></address></result></response>`
+	})
+
+	addrs, err := p.Addresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Address{
+		{Name: "web", IPAddress: "10.1.1.1", Tags: []string{"prod"}, DisableOverride: "no"},
+		{Name: "site", FQDN: "example.com"},
+	}
+
+	if !reflect.DeepEqual(addrs.Addresses, want) {
+		t.Errorf("got %+v", addrs.Addresses)
+	}
+
+	if addrs.Addresses[0].Type() != "ip" || addrs.Addresses[1].Type() != "fqdn" {
+		t.Error("unexpected address types")
+	}
+}
+
+func TestAddressToXML(t *testing.T) {
+	a := &Address{Name: "web", IPRange: "10.1.1.1-10.1.1.5", Description: "a<b", Tags: []string{"prod"}}
+
+	x, err := a.toXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := xml.Marshal(x)
+	if want := `<entry name="web"><ip-range>10.1.1.1-10.1.1.5</ip-range><description>a&lt;b</description><tag><member>prod</member></tag></entry>`; string(b) != want {
+		t.Errorf("got %s", b)
+	}
+
+	for _, bad := range []Address{{Name: "none"}, {Name: "two", IPAddress: "10.1.1.1", FQDN: "example.com"}} {
+		if _, err := bad.toXML(); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
+
+func TestCreateSharedObjectsOnFirewall(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	errs := []error{
+		p.CreateSharedAddress("web", "ip", "10.1.1.1", ""),
+		p.CreateSharedStaticGroup("web-servers", "web", ""),
+		p.CreateSharedDynamicGroup("tagged", "'web'", ""),
+		p.CreateSharedService("web", "tcp", "80", ""),
+		p.CreateSharedServiceGroup("web-ports", "web"),
+	}
+
+	for i, err := range errs {
+		if err == nil || err.Error() != "you can only create shared objects when connected to a Panorama device" {
+			t.Errorf("%d: err = %v", i, err)
+		}
+	}
+
+	if len(d.sent("")) != 0 {
+		t.Error("shared objects should not be sent to a firewall")
+	}
+}
+
+func TestEditAddress(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+	p.DeviceType = "panorama"
+
+	addr := &Address{Name: "web's", FQDN: "example.com", Tags: []string{"prod"}}
+	if err := p.EditAddress(addr, DeviceGroupLocation("dg1")); err != nil {
+		t.Fatal(err)
+	}
+
+	q := d.sent("edit")[0]
+	if want := `/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='dg1']/address/entry[@name="web's"]`; q.Get("xpath") != want {
+		t.Errorf("got xpath %s", q.Get("xpath"))
+	}
+
+	if want := `<entry name="web&#39;s"><fqdn>example.com</fqdn><tag><member>prod</member></tag></entry>`; q.Get("element") != want {
+		t.Errorf("got element %s", q.Get("element"))
+	}
+
+	if err := p.EditAddress(&Address{Name: "web"}); err == nil {
+		t.Error("expected an error for an address without a value")
+	}
+
+	if err := p.EditAddress(nil); err == nil {
+		t.Error("expected an error for a missing address")
+	}
+}
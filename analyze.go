@@ -0,0 +1,275 @@
+package panos
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ObjectAnalysis contains the findings of AnalyzeObjects for a single location (shared, a device-group or a vsys).
+type ObjectAnalysis struct {
+	Location Location
+	// Unused contains the address objects, address groups, service objects and service groups that nothing refers to.
+	Unused []UnusedObject
+	// DuplicateObjects contains each set of address or service objects that have the same value under different
+	// names.
+	DuplicateObjects []DuplicateObjects
+	// DuplicateGroups contains each set of address or service groups that have the same members (or the same dynamic
+	// filter) under different names.
+	DuplicateGroups []DuplicateObjects
+}
+
+// UnusedObject describes an object that is not referenced anywhere. ObjectType is one of: address, address-group,
+// service or service-group.
+type UnusedObject struct {
+	ObjectType string
+	Name       string
+}
+
+// DuplicateObjects describes a set of objects of the same type that have the same value, such as "ip:10.1.1.1/32",
+// "tcp:443", "tcp:443:1024-65535;timeout=7200" or the sorted members of a group. ObjectType is one of: address,
+// address-group, service or service-group.
+type DuplicateObjects struct {
+	ObjectType string
+	Value      string
+	Names      []string
+}
+
+// duplicates groups the names of objects by their value, keeping the order the values were first seen in.
+type duplicates struct {
+	values []string
+	names  map[string][]string
+}
+
+// AnalyzeObjects looks through the address objects, address groups, service objects and service groups in shared and
+// every device-group (or every vsys on a firewall), and reports the ones that are unused, or duplicates of each other,
+// for each location. An object is only reported as unused if no group, dynamic group filter, or security, NAT, policy
+// based forwarding, decryption or QoS rule refers to it, by name or by one of its tags. References by name are
+// resolved the same way as WhereUsed, so a shared object is not counted as used by a device-group that has its own
+// object of the same name, and a dynamic group filter only counts for objects in its own location and shared.
+func (p *PaloAlto) AnalyzeObjects() ([]ObjectAnalysis, error) {
+	return p.AnalyzeObjectsContext(context.Background())
+}
+
+// AnalyzeObjectsContext is the same as AnalyzeObjects, but uses the given context for all API requests.
+func (p *PaloAlto) AnalyzeObjectsContext(ctx context.Context) ([]ObjectAnalysis, error) {
+	var results []ObjectAnalysis
+	used := map[objectKey]bool{}
+	// tagged contains the tags used by dynamic group filters, by the xpath of the location of the filter.
+	tagged := map[string]map[string]bool{}
+
+	locs, err := p.usageLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := p.objectIndex(ctx, locs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range locs {
+		uses, err := p.locationUses(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, u := range uses {
+			if u.ref.Field == "dynamic-filter" {
+				if tagged[u.base] == nil {
+					tagged[u.base] = map[string]bool{}
+				}
+
+				tagged[u.base][u.object] = true
+				continue
+			}
+
+			if key, _, ok := idx.resolve(u); ok {
+				used[key] = true
+			}
+		}
+	}
+
+	isUsed := func(key objectKey, tags []string) bool {
+		if used[key] {
+			return true
+		}
+
+		for base, filterTags := range tagged {
+			if base != key.location && base != "/config/shared" && key.location != "/config/shared" {
+				continue
+			}
+
+			for _, t := range tags {
+				if filterTags[t] {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	for _, l := range locs {
+		var addrDups, svcDups, addrGroupDups, svcGroupDups duplicates
+		analysis := ObjectAnalysis{Location: l}
+
+		base, err := l.Xpath()
+		if err != nil {
+			return nil, err
+		}
+
+		unused := func(objecttype, name string) {
+			analysis.Unused = append(analysis.Unused, UnusedObject{ObjectType: objecttype, Name: name})
+		}
+
+		addrs, err := p.AddressesContext(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range addrs.Addresses {
+			if !isUsed(objectKey{location: base, objecttype: "address", name: a.Name}, a.Tags) {
+				unused("address", a.Name)
+			}
+
+			addrDups.add(addressValue(a), a.Name)
+		}
+
+		addrGroups, err := p.AddressGroupsContext(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range addrGroups.Groups {
+			if !isUsed(objectKey{location: base, objecttype: "address-group", name: g.Name}, nil) {
+				unused("address-group", g.Name)
+			}
+
+			if g.DynamicFilter != "" {
+				addrGroupDups.add("filter:"+strings.TrimSpace(g.DynamicFilter), g.Name)
+				continue
+			}
+
+			addrGroupDups.add(membersValue(g.Members), g.Name)
+		}
+
+		services, err := p.ServicesContext(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range services.Services {
+			if !isUsed(objectKey{location: base, objecttype: "service", name: s.Name}, s.Tags) {
+				unused("service", s.Name)
+			}
+
+			svcDups.add(serviceValue(s), s.Name)
+		}
+
+		svcGroups, err := p.ServiceGroupsContext(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range svcGroups.Groups {
+			if !isUsed(objectKey{location: base, objecttype: "service-group", name: g.Name}, nil) {
+				unused("service-group", g.Name)
+			}
+
+			svcGroupDups.add(membersValue(g.Members), g.Name)
+		}
+
+		analysis.DuplicateObjects = append(addrDups.found("address"), svcDups.found("service")...)
+		analysis.DuplicateGroups = append(addrGroupDups.found("address-group"), svcGroupDups.found("service-group")...)
+
+		results = append(results, analysis)
+	}
+
+	return results, nil
+}
+
+// add records an object with the given value. Objects without a value are ignored.
+func (d *duplicates) add(value, name string) {
+	if value == "" {
+		return
+	}
+
+	if d.names == nil {
+		d.names = map[string][]string{}
+	}
+
+	if _, ok := d.names[value]; !ok {
+		d.values = append(d.values, value)
+	}
+
+	d.names[value] = append(d.names[value], name)
+}
+
+// found returns each value that is shared by more than one object.
+func (d *duplicates) found(objecttype string) []DuplicateObjects {
+	var dups []DuplicateObjects
+
+	for _, v := range d.values {
+		if len(d.names[v]) > 1 {
+			dups = append(dups, DuplicateObjects{ObjectType: objecttype, Value: v, Names: d.names[v]})
+		}
+	}
+
+	return dups
+}
+
+// addressValue returns the type and value of an address object, i.e. "ip:10.1.1.1/32". A single IP address is given
+// its full netmask, so that it matches the same address written with one.
+func addressValue(a Address) string {
+	value := strings.TrimSpace(a.IPAddress + a.IPRange + a.IPWildcard + a.FQDN)
+
+	switch a.Type() {
+	case "":
+		return ""
+	case "ip":
+		if !strings.Contains(value, "/") && strings.Contains(value, ":") {
+			value += "/128"
+		} else if !strings.Contains(value, "/") {
+			value += "/32"
+		}
+	case "fqdn":
+		value = strings.ToLower(value)
+	}
+
+	return a.Type() + ":" + value
+}
+
+// serviceValue returns the protocol, port, source port and any timeout overrides of a service object, i.e. "tcp:443",
+// "udp:53:1024-65535" or "tcp:443;timeout=7200;halfclose-timeout=60".
+func serviceValue(s Service) string {
+	if s.Protocol() == "" {
+		return ""
+	}
+
+	value := s.Protocol() + ":" + strings.Replace(s.TCPPort+s.UDPPort+s.SCTPPort, " ", "", -1)
+
+	if s.SourcePort != "" {
+		value += ":" + strings.Replace(s.SourcePort, " ", "", -1)
+	}
+
+	for _, t := range []struct{ name, value string }{
+		{"timeout", s.Timeout},
+		{"halfclose-timeout", s.HalfcloseTimeout},
+		{"timewait-timeout", s.TimewaitTimeout},
+	} {
+		if t.value != "" {
+			value += ";" + t.name + "=" + t.value
+		}
+	}
+
+	return value
+}
+
+// membersValue returns the members of a group, sorted and separated by a comma.
+func membersValue(members []string) string {
+	m := append([]string{}, members...)
+	sort.Strings(m)
+
+	return strings.Join(m, ",")
+}
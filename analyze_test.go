@@ -0,0 +1,89 @@
+package panos
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeObjectsUnused(t *testing.T) {
+	p, _ := newFakePanorama(t, map[string]string{
+		"shared/address": `<entry name="web"><ip-netmask>10.1.1.1</ip-netmask></entry>` +
+			`<entry name="tagged"><fqdn>tagged.example.com</fqdn><tag><member>prod</member></tag></entry>`,
+		"'dg1']/address": `<entry name="web"><ip-netmask>10.2.2.2</ip-netmask></entry>` +
+			`<entry name="next-hop"><ip-netmask>10.3.3.3</ip-netmask></entry>`,
+		"'dg2']/address":       `<entry name="dg2-tagged"><ip-netmask>10.4.4.4</ip-netmask><tag><member>prod</member></tag></entry>`,
+		"'dg1']/address-group": `<entry name="dynamic"><dynamic><filter>prod</filter></dynamic></entry>`,
+		"'dg1']/pre-rulebase/security/rules": `<entry name="rule"><source><member>web</member></source>` +
+			`<destination><member>dynamic</member></destination></entry>`,
+		"'dg1']/post-rulebase/pbf/rules": `<entry name="pbf"><destination><member>next-hop</member></destination></entry>`,
+	})
+
+	results, err := p.AnalyzeObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unused := map[string][]UnusedObject{}
+	for _, r := range results {
+		unused[r.Location.String()] = r.Unused
+	}
+
+	// The shared web address is shadowed by the one in dg1, and the dynamic group in dg1 doesn't match objects in dg2.
+	want := map[string][]UnusedObject{
+		"shared":           {{ObjectType: "address", Name: "web"}},
+		"device-group dg1": nil,
+		"device-group dg2": {{ObjectType: "address", Name: "dg2-tagged"}},
+	}
+
+	if !reflect.DeepEqual(unused, want) {
+		t.Errorf("got %+v", unused)
+	}
+}
+
+func TestAnalyzeObjectsDuplicates(t *testing.T) {
+	p, _ := newFakePanorama(t, map[string]string{
+		"shared/address": `<entry name="a"><ip-netmask>10.1.1.1</ip-netmask></entry>` +
+			`<entry name="b"><ip-netmask>10.1.1.1/32</ip-netmask></entry><entry name="c"><fqdn>Example.com</fqdn></entry>`,
+		"shared/service": `<entry name="https"><protocol><tcp><port>443</port></tcp></protocol></entry>` +
+			`<entry name="https-2"><protocol><tcp><port>443</port></tcp></protocol></entry>` +
+			`<entry name="https-long"><protocol><tcp><port>443</port><override><yes><timeout>7200</timeout></yes></override></tcp></protocol></entry>`,
+		"shared/service-group": `<entry name="g1"><members><member>https</member><member>https-2</member></members></entry>` +
+			`<entry name="g2"><members><member>https-2</member><member>https</member></members></entry>`,
+	})
+
+	results, err := p.AnalyzeObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DuplicateObjects{
+		{ObjectType: "address", Value: "ip:10.1.1.1/32", Names: []string{"a", "b"}},
+		{ObjectType: "service", Value: "tcp:443", Names: []string{"https", "https-2"}},
+	}
+
+	if !reflect.DeepEqual(results[0].DuplicateObjects, want) {
+		t.Errorf("got %+v", results[0].DuplicateObjects)
+	}
+
+	if len(results[0].DuplicateGroups) != 1 || results[0].DuplicateGroups[0].Value != "https,https-2" {
+		t.Errorf("got %+v", results[0].DuplicateGroups)
+	}
+}
+
+func TestServiceValue(t *testing.T) {
+	tests := []struct {
+		svc  Service
+		want string
+	}{
+		{Service{TCPPort: "80, 443"}, "tcp:80,443"},
+		{Service{UDPPort: "53", SourcePort: "1024-65535"}, "udp:53:1024-65535"},
+		{Service{TCPPort: "443", Timeout: "7200", TimewaitTimeout: "5"}, "tcp:443;timeout=7200;timewait-timeout=5"},
+		{Service{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := serviceValue(tt.svc); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.svc, got, tt.want)
+		}
+	}
+}
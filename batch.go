@@ -0,0 +1,230 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// batchSize is the default number of operations sent in each multi-config request.
+const batchSize = 500
+
+// Batch accumulates set, edit and delete operations on the configuration, and submits them together, which is much
+// faster than making one request per change. Create one using NewBatch, add operations to it, and then call Submit:
+//
+//	b := pa.NewBatch()
+//	for _, host := range hosts {
+//		entry := fmt.Sprintf("<entry name=\"%s\"><ip-netmask>%s</ip-netmask></entry>", panos.EscapeXML(host.Name),
+//			panos.EscapeXML(host.IP))
+//		b.Set(addrXpath, entry)
+//	}
+//
+//	if err := b.Submit(); err != nil {
+//		...
+//	}
+//
+// On PAN-OS 9.0 and later, operations are sent Size at a time as multi-config requests. Each request is applied as
+// a whole, so if one of its operations fails, none of the operations in that request are applied. On older versions,
+// or if the version of the device isn't known, each operation is sent on its own, in order. Either way, Submit stops
+// at the first failure and returns a *BatchError describing it.
+type Batch struct {
+	// Size is the number of operations sent in each multi-config request (default 500).
+	Size int
+
+	p   *PaloAlto
+	ops []batchOp
+}
+
+// batchOp contains each individual operation of a batch.
+type batchOp struct {
+	action  string
+	xpath   string
+	element string
+	err     error
+}
+
+// xmlMultiConfig is used for parsing the response to a multi-config request.
+type xmlMultiConfig struct {
+	XMLName xml.Name          `xml:"response"`
+	Status  string            `xml:"status,attr"`
+	Code    string            `xml:"code,attr"`
+	Result  xmlMultiConfigOps `xml:"result>multi-config"`
+}
+
+// xmlMultiConfigOps is used for parsing the result of each operation in a multi-config request.
+type xmlMultiConfigOps struct {
+	Ops []xmlMultiConfigOp `xml:",any"`
+}
+
+// xmlMultiConfigOp is used for parsing the result of an individual operation in a multi-config request.
+type xmlMultiConfigOp struct {
+	XMLName xml.Name
+	ID      string       `xml:"id,attr"`
+	Status  string       `xml:"status,attr"`
+	Code    string       `xml:"code,attr"`
+	Message errorMessage `xml:"msg"`
+}
+
+// NewBatch returns an empty batch of configuration changes for the device.
+func (p *PaloAlto) NewBatch() *Batch {
+	return &Batch{
+		Size: batchSize,
+		p:    p,
+	}
+}
+
+// Set adds an operation to the batch that adds the element to the configuration at xpath, just like ConfigSet.
+func (b *Batch) Set(xpath string, element interface{}) *Batch {
+	return b.add("set", xpath, element)
+}
+
+// Edit adds an operation to the batch that replaces the configuration at xpath with the element, just like
+// ConfigEdit.
+func (b *Batch) Edit(xpath string, element interface{}) *Batch {
+	return b.add("edit", xpath, element)
+}
+
+// Delete adds an operation to the batch that removes the configuration at xpath, just like ConfigDelete.
+func (b *Batch) Delete(xpath string) *Batch {
+	b.ops = append(b.ops, batchOp{action: "delete", xpath: xpath})
+
+	return b
+}
+
+// Len returns the number of operations in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// add adds an operation that has an element to the batch. Any error converting the element is reported by Submit.
+func (b *Batch) add(action, xpath string, element interface{}) *Batch {
+	e, err := configElement(element)
+	b.ops = append(b.ops, batchOp{action: action, xpath: xpath, element: e, err: err})
+
+	return b
+}
+
+// Submit sends all of the operations in the batch to the device, in the order they were added. If an operation
+// fails, then a *BatchError is returned, which says which operation failed and how many were applied before it.
+func (b *Batch) Submit() error {
+	return b.SubmitContext(context.Background())
+}
+
+// SubmitContext is the same as Submit, but uses the given context for all API requests.
+func (b *Batch) SubmitContext(ctx context.Context) error {
+	for i, op := range b.ops {
+		if op.xpath == "" {
+			return &BatchError{Index: i, Action: op.action, Err: errors.New("you must specify an xpath")}
+		}
+
+		if op.err != nil {
+			return &BatchError{Index: i, Action: op.action, XPath: op.xpath, Err: op.err}
+		}
+	}
+
+	ver := splitSWVersion(b.p.SoftwareVersion)
+	if ver[0] < 9 {
+		return b.submitSequential(ctx)
+	}
+
+	size := b.Size
+	if size <= 0 {
+		size = batchSize
+	}
+
+	for start := 0; start < len(b.ops); start += size {
+		end := start + size
+		if end > len(b.ops) {
+			end = len(b.ops)
+		}
+
+		if err := b.submitMulti(ctx, start, end); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// submitMulti sends the operations from start up to (but not including) end as a single multi-config request. Each
+// operation's id is its position in the batch, plus one.
+func (b *Batch) submitMulti(ctx context.Context, start, end int) error {
+	var result xmlMultiConfig
+	var element strings.Builder
+
+	element.WriteString("<multi-config>")
+	for i, op := range b.ops[start:end] {
+		fmt.Fprintf(&element, "<%s id=\"%d\" xpath=\"%s\">%s</%s>", op.action, start+i+1, escapeXML(op.xpath), op.element, op.action)
+	}
+	element.WriteString("</multi-config>")
+
+	query := map[string]string{
+		"type":    "config",
+		"action":  "multi-config",
+		"element": element.String(),
+		"key":     b.p.Key,
+	}
+
+	resp := b.p.send(ctx, "post", query)
+	if resp.Error != nil {
+		return &BatchError{Index: start, Action: b.ops[start].action, XPath: b.ops[start].xpath, Applied: start, Err: resp.Error}
+	}
+
+	if err := xml.Unmarshal(resp.Body, &result); err != nil {
+		return &BatchError{Index: start, Action: b.ops[start].action, XPath: b.ops[start].xpath, Applied: start, Err: err}
+	}
+
+	if result.Status == "success" {
+		return nil
+	}
+
+	apiErr := newAPIError(resp, query)
+	index := start
+
+	for _, op := range result.Result.Ops {
+		if op.Status == "success" {
+			continue
+		}
+
+		if id, err := strconv.Atoi(op.ID); err == nil && id > start && id <= end {
+			index = id - 1
+		}
+
+		if op.Code != "" {
+			apiErr.Code = op.Code
+		}
+
+		for _, line := range append(op.Message.Lines, op.Message.Text) {
+			if l := strings.TrimSpace(line); l != "" {
+				apiErr.Messages = append(apiErr.Messages, l)
+			}
+		}
+
+		break
+	}
+
+	apiErr.Action = b.ops[index].action
+	apiErr.XPath = b.ops[index].xpath
+
+	return &BatchError{Index: index, Action: b.ops[index].action, XPath: b.ops[index].xpath, Applied: start, Err: apiErr}
+}
+
+// submitSequential sends each operation on its own, for devices that do not support multi-config requests.
+func (b *Batch) submitSequential(ctx context.Context) error {
+	for i, op := range b.ops {
+		var element interface{}
+
+		if op.action != "delete" {
+			element = op.element
+		}
+
+		if err := b.p.configWrite(ctx, op.action, op.xpath, element, nil); err != nil {
+			return &BatchError{Index: i, Action: op.action, XPath: op.xpath, Applied: i, Err: err}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package panos
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSWVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []int
+	}{
+		{"10.1.3", []int{10, 1, 3}},
+		{"9.0.0-h3", []int{9, 0, 0}},
+		{"", []int{0, 0, 0}},
+		{"unknown", []int{0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		if got := splitSWVersion(tt.version); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestBatchMultiConfig(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	b := p.NewBatch()
+	b.Size = 2
+	for _, name := range []string{"a", "b", "c"} {
+		b.Set("/config/shared/address", `<entry name="`+name+`"><fqdn>example.com</fqdn></entry>`)
+	}
+	b.Delete("/config/shared/address/entry[@name='old']")
+
+	if err := b.Submit(); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := d.sent("multi-config")
+	if len(sent) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(sent))
+	}
+
+	want := `<multi-config><set id="3" xpath="/config/shared/address"><entry name="c"><fqdn>example.com</fqdn></entry></set>` +
+		`<delete id="4" xpath="/config/shared/address/entry[@name=&#39;old&#39;]"></delete></multi-config>`
+	if element := sent[1].Get("element"); element != want {
+		t.Errorf("got %s", element)
+	}
+}
+
+func TestBatchMultiConfigError(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		if !strings.Contains(q.Get("element"), `id="3"`) {
+			return success
+		}
+
+		return `<response status="error" code="12"><result><multi-config><set id="3" status="success"/>` +
+			`<set id="4" status="error" code="12"><msg><line>bad element</line></msg></set></multi-config></result></response>`
+	})
+
+	b := p.NewBatch()
+	b.Size = 2
+	for i := 0; i < 4; i++ {
+		b.Set("/config/shared/address", "<entry/>")
+	}
+
+	err := b.Submit()
+
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("err = %v, want a *BatchError", err)
+	}
+
+	if batchErr.Index != 3 || batchErr.Applied != 2 || batchErr.Action != "set" {
+		t.Errorf("got %+v", batchErr)
+	}
+
+	if !strings.Contains(batchErr.Error(), "bad element") {
+		t.Errorf("got %q", batchErr.Error())
+	}
+}
+
+func TestBatchSequential(t *testing.T) {
+	for _, version := range []string{"8.1.0", ""} {
+		p, d := newFakeDevice(t, func(q url.Values) string {
+			if q.Get("action") == "delete" {
+				return `<response status="error" code="7"><msg><line>No such node</line></msg></response>`
+			}
+
+			return success
+		})
+		p.SoftwareVersion = version
+
+		b := p.NewBatch()
+		b.Set("/config/shared/address", "<entry/>").Edit("/config/shared/address/entry[@name='a']", "<entry/>").Delete("/config/x")
+
+		batchErr, ok := b.Submit().(*BatchError)
+		if !ok || batchErr.Index != 2 || batchErr.Applied != 2 || !IsNotFound(batchErr) {
+			t.Errorf("%q: got %+v", version, batchErr)
+		}
+
+		if n := len(d.sent("multi-config")); n != 0 {
+			t.Errorf("%q: sent %d multi-config requests", version, n)
+		}
+	}
+}
+
+func TestRevertCandidateUnknownVersion(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+	p.SoftwareVersion = ""
+
+	if err := p.RevertCandidate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd := d.sent("op")[0].Get("cmd"); cmd != "<load><config><from>running-config.xml</from></config></load>" {
+		t.Errorf("unexpected command %s", cmd)
+	}
+}
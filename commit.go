@@ -0,0 +1,148 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+)
+
+// CommitOptions contains the settings for a commit. Specifying Admins will perform a partial commit of only the
+// changes made by those administrators. ExcludeDeviceAndNetwork and ExcludeSharedObjects leave those sections of
+// the configuration out of the commit, and Force will commit even if another commit is pending or in progress.
+type CommitOptions struct {
+	Description             string
+	Admins                  []string
+	ExcludeDeviceAndNetwork bool
+	ExcludeSharedObjects    bool
+	Force                   bool
+}
+
+// xmlCommit is used for creating the commit command.
+type xmlCommit struct {
+	XMLName xml.Name `xml:"commit"`
+	xmlCommitOptions
+	Force *xmlCommitOptions `xml:"force,omitempty"`
+}
+
+// xmlCommitOptions is used for creating the options of a commit, which are nested under <force> for a forced commit.
+type xmlCommitOptions struct {
+	Description string            `xml:"description,omitempty"`
+	Partial     *xmlPartialCommit `xml:"partial,omitempty"`
+}
+
+// xmlPartialCommit is used for creating a partial commit.
+type xmlPartialCommit struct {
+	Admins           *xmlMembers `xml:"admin,omitempty"`
+	DeviceAndNetwork string      `xml:"device-and-network,omitempty"`
+	SharedObject     string      `xml:"shared-object,omitempty"`
+}
+
+// command builds the commit command for the given options.
+func (o *CommitOptions) command() (string, error) {
+	var cmd xmlCommit
+	var opts xmlCommitOptions
+
+	if o != nil {
+		opts.Description = o.Description
+		partial := &xmlPartialCommit{Admins: newMembers(o.Admins...)}
+
+		if o.ExcludeDeviceAndNetwork {
+			partial.DeviceAndNetwork = "excluded"
+		}
+
+		if o.ExcludeSharedObjects {
+			partial.SharedObject = "excluded"
+		}
+
+		if *partial != (xmlPartialCommit{}) {
+			opts.Partial = partial
+		}
+	}
+
+	if o != nil && o.Force {
+		cmd.Force = &opts
+	} else {
+		cmd.xmlCommitOptions = opts
+	}
+
+	b, err := xml.Marshal(&cmd)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// CommitWithOptions issues a commit on the device using the given options, and returns the ID of the commit job.
+// If there are no changes to commit, then the job ID will be empty. Use WaitForJob to find out if the commit succeeded.
+func (p *PaloAlto) CommitWithOptions(options *CommitOptions) (string, error) {
+	return p.CommitWithOptionsContext(context.Background(), options)
+}
+
+// CommitWithOptionsContext is the same as CommitWithOptions, but uses the given context for all API requests.
+func (p *PaloAlto) CommitWithOptionsContext(ctx context.Context, options *CommitOptions) (string, error) {
+	var commit commitResponse
+
+	cmd, err := options.command()
+	if err != nil {
+		return "", err
+	}
+
+	query := map[string]string{
+		"type": "commit",
+		"cmd":  cmd,
+		"key":  p.Key,
+	}
+
+	resp := p.send(ctx, "get", query)
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &commit); err != nil {
+		return "", err
+	}
+
+	if commit.Status != "success" {
+		return "", newAPIError(resp, query)
+	}
+
+	return commit.Job, nil
+}
+
+// Validate runs a full validation of the candidate configuration without committing it, and waits for the
+// validation job to finish. Any errors or warnings are found in the job's Details and Warnings. If the validation
+// fails, then the job is returned along with an error.
+func (p *PaloAlto) Validate() (*Job, error) {
+	return p.ValidateContext(context.Background())
+}
+
+// ValidateContext is the same as Validate, but uses the given context for all API requests.
+func (p *PaloAlto) ValidateContext(ctx context.Context) (*Job, error) {
+	var validate commitResponse
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  "<validate><full></full></validate>",
+		"key":  p.Key,
+	}
+
+	resp := p.send(ctx, "get", query)
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &validate); err != nil {
+		return nil, err
+	}
+
+	if validate.Status != "success" {
+		return nil, newAPIError(resp, query)
+	}
+
+	if validate.Job == "" {
+		return nil, errors.New("the device did not return a validation job")
+	}
+
+	return p.WaitForJob(ctx, validate.Job)
+}
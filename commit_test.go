@@ -0,0 +1,69 @@
+package panos
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCommitOptionsCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *CommitOptions
+		want    string
+	}{
+		{"none", nil, "<commit></commit>"},
+		{"description", &CommitOptions{Description: "a & b"}, "<commit><description>a &amp; b</description></commit>"},
+		{
+			"partial",
+			&CommitOptions{Admins: []string{"admin", "ops"}, ExcludeSharedObjects: true},
+			"<commit><partial><admin><member>admin</member><member>ops</member></admin><shared-object>excluded</shared-object></partial></commit>",
+		},
+		{
+			"force",
+			&CommitOptions{Force: true, ExcludeDeviceAndNetwork: true},
+			"<commit><force><partial><device-and-network>excluded</device-and-network></partial></force></commit>",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.options.command()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCommitWithOptions(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success" code="19"><result><msg><line>Commit job enqueued with jobid 12</line></msg><job>12</job></result></response>`
+	})
+
+	id, err := p.CommitWithOptions(&CommitOptions{Description: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "12" {
+		t.Errorf("job id = %q, want 12", id)
+	}
+
+	if cmd := d.sent("commit")[0].Get("cmd"); cmd != "<commit><description>test</description></commit>" {
+		t.Errorf("unexpected command %s", cmd)
+	}
+}
+
+func TestCommitNothingToDo(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success" code="19"><msg>There are no changes to commit.</msg></response>`
+	})
+
+	id, err := p.CommitWithOptions(nil)
+	if err != nil || id != "" {
+		t.Errorf("got id %q and err %v", id, err)
+	}
+}
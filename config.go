@@ -0,0 +1,276 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConfigChanges contains a slice of all uncommitted changes in the candidate configuration.
+type ConfigChanges struct {
+	Changes []ConfigChange
+}
+
+// ConfigChange contains information about each individual uncommitted change. Action is one of: ADD, EDIT,
+// DELETE, RENAME or MOVE.
+type ConfigChange struct {
+	XPath         string
+	Owner         string
+	Action        string
+	AdminHistory  string
+	ComponentType string
+}
+
+// candidateConfig is used for parsing the candidate configuration.
+type candidateConfig struct {
+	XMLName xml.Name `xml:"response"`
+	Status  string   `xml:"status,attr"`
+	Code    string   `xml:"code,attr"`
+	Result  struct {
+		Config []byte `xml:",innerxml"`
+	} `xml:"result"`
+}
+
+// xmlConfigChanges is used for parsing all uncommitted changes.
+type xmlConfigChanges struct {
+	XMLName xml.Name          `xml:"response"`
+	Status  string            `xml:"status,attr"`
+	Code    string            `xml:"code,attr"`
+	Changes []xmlConfigChange `xml:"result>journal>entry"`
+}
+
+// xmlConfigChange is used for parsing each individual uncommitted change.
+type xmlConfigChange struct {
+	XPath         string `xml:"xpath"`
+	Owner         string `xml:"owner"`
+	Action        string `xml:"action"`
+	AdminHistory  string `xml:"admin-history"`
+	ComponentType string `xml:"component-type"`
+}
+
+// PendingChanges returns all of the changes in the candidate configuration that have not been committed yet,
+// along with the administrator who made them.
+func (p *PaloAlto) PendingChanges() (*ConfigChanges, error) {
+	return p.PendingChangesContext(context.Background())
+}
+
+// PendingChangesContext is the same as PendingChanges, but uses the given context for all API requests.
+func (p *PaloAlto) PendingChangesContext(ctx context.Context) (*ConfigChanges, error) {
+	var parsedChanges xmlConfigChanges
+	var changes ConfigChanges
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  "<show><config><list><changes></changes></list></config></show>",
+		"key":  p.Key,
+	}
+
+	changeData := p.send(ctx, "get", query)
+	if changeData.Error != nil {
+		return nil, changeData.Error
+	}
+
+	if err := xml.Unmarshal(changeData.Body, &parsedChanges); err != nil {
+		return nil, err
+	}
+
+	if parsedChanges.Status != "success" {
+		return nil, newAPIError(changeData, query)
+	}
+
+	for _, c := range parsedChanges.Changes {
+		changes.Changes = append(changes.Changes, ConfigChange{
+			XPath:         strings.TrimSpace(c.XPath),
+			Owner:         strings.TrimSpace(c.Owner),
+			Action:        strings.TrimSpace(c.Action),
+			AdminHistory:  strings.TrimSpace(c.AdminHistory),
+			ComponentType: strings.TrimSpace(c.ComponentType),
+		})
+	}
+
+	return &changes, nil
+}
+
+// RevertCandidate discards all uncommitted changes, reverting the candidate configuration back to the running
+// configuration. On devices running a version older than 8.1.0, the running configuration is loaded instead.
+func (p *PaloAlto) RevertCandidate() error {
+	return p.RevertCandidateContext(context.Background())
+}
+
+// RevertCandidateContext is the same as RevertCandidate, but uses the given context for all API requests.
+func (p *PaloAlto) RevertCandidateContext(ctx context.Context) error {
+	var reqError requestError
+	ver := splitSWVersion(p.SoftwareVersion)
+	cmd := "<revert><config></config></revert>"
+
+	if ver[0] < 8 || (ver[0] == 8 && ver[1] < 1) {
+		cmd = "<load><config><from>running-config.xml</from></config></load>"
+	}
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  cmd,
+		"key":  p.Key,
+	}
+
+	resp := p.send(ctx, "post", query)
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+		return err
+	}
+
+	if reqError.Status != "success" {
+		return newAPIError(resp, query)
+	}
+
+	return nil
+}
+
+// ExportRunningConfig returns the running configuration of the device as XML.
+func (p *PaloAlto) ExportRunningConfig() ([]byte, error) {
+	return p.ExportRunningConfigContext(context.Background())
+}
+
+// ExportRunningConfigContext is the same as ExportRunningConfig, but uses the given context for all API requests.
+func (p *PaloAlto) ExportRunningConfigContext(ctx context.Context) ([]byte, error) {
+	var reqError requestError
+
+	query := map[string]string{
+		"type":     "export",
+		"category": "configuration",
+		"key":      p.Key,
+	}
+
+	resp := p.send(ctx, "get", query)
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	// The configuration is returned as-is, so only a <response> element means that the export failed.
+	if err := xml.Unmarshal(resp.Body, &reqError); err == nil && reqError.Status != "success" {
+		return nil, newAPIError(resp, query)
+	}
+
+	return resp.Body, nil
+}
+
+// ExportCandidateConfig returns the candidate configuration of the device as XML, which includes any
+// changes that have not been committed yet.
+func (p *PaloAlto) ExportCandidateConfig() ([]byte, error) {
+	return p.ExportCandidateConfigContext(context.Background())
+}
+
+// ExportCandidateConfigContext is the same as ExportCandidateConfig, but uses the given context for all API requests.
+func (p *PaloAlto) ExportCandidateConfigContext(ctx context.Context) ([]byte, error) {
+	var config candidateConfig
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  "<show><config><candidate></candidate></config></show>",
+		"key":  p.Key,
+	}
+
+	configData := p.send(ctx, "get", query)
+	if configData.Error != nil {
+		return nil, configData.Error
+	}
+
+	if err := xml.Unmarshal(configData.Body, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Status != "success" {
+		return nil, newAPIError(configData, query)
+	}
+
+	return []byte(strings.TrimSpace(string(config.Result.Config))), nil
+}
+
+// ImportConfig uploads a configuration file to the device, and saves it under the given name. The configuration
+// is not used until it is loaded with LoadConfig.
+func (p *PaloAlto) ImportConfig(name string, config io.Reader) error {
+	return p.ImportConfigContext(context.Background(), name, config)
+}
+
+// ImportConfigContext is the same as ImportConfig, but uses the given context for all API requests.
+func (p *PaloAlto) ImportConfigContext(ctx context.Context, name string, config io.Reader) error {
+	var reqError requestError
+
+	if name == "" {
+		return errors.New("you must specify a name for the imported configuration")
+	}
+
+	query := map[string]string{
+		"type":     "import",
+		"category": "configuration",
+		"key":      p.Key,
+	}
+
+	resp := p.upload(ctx, query, name, config)
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+		return err
+	}
+
+	if reqError.Status != "success" {
+		return newAPIError(resp, query)
+	}
+
+	return nil
+}
+
+// LoadConfig replaces the candidate configuration with the named configuration file, such as one uploaded with
+// ImportConfig or saved with SaveConfig. You must commit the configuration for it to take effect.
+func (p *PaloAlto) LoadConfig(name string) error {
+	return p.LoadConfigContext(context.Background(), name)
+}
+
+// LoadConfigContext is the same as LoadConfig, but uses the given context for all API requests.
+func (p *PaloAlto) LoadConfigContext(ctx context.Context, name string) error {
+	return p.configFileOp(ctx, fmt.Sprintf("<load><config><from>%s</from></config></load>", escapeXML(name)))
+}
+
+// SaveConfig saves the candidate configuration to a named configuration file on the device.
+func (p *PaloAlto) SaveConfig(name string) error {
+	return p.SaveConfigContext(context.Background(), name)
+}
+
+// SaveConfigContext is the same as SaveConfig, but uses the given context for all API requests.
+func (p *PaloAlto) SaveConfigContext(ctx context.Context, name string) error {
+	return p.configFileOp(ctx, fmt.Sprintf("<save><config><to>%s</to></config></save>", escapeXML(name)))
+}
+
+// configFileOp runs the given operational command for loading or saving a configuration file.
+func (p *PaloAlto) configFileOp(ctx context.Context, cmd string) error {
+	var reqError requestError
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  cmd,
+		"key":  p.Key,
+	}
+
+	resp := p.send(ctx, "post", query)
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+		return err
+	}
+
+	if reqError.Status != "success" {
+		return newAPIError(resp, query)
+	}
+
+	return nil
+}
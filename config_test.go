@@ -0,0 +1,184 @@
+package panos
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPendingChanges(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result><journal><entry>
+			<xpath>/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='web']</xpath>
+			<owner> admin </owner><action> EDIT </action><admin-history>admin</admin-history>
+			<component-type>address</component-type></entry></journal></result></response>`
+	})
+
+	changes, err := p.PendingChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ConfigChange{{
+		XPath:         "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='web']",
+		Owner:         "admin",
+		Action:        "EDIT",
+		AdminHistory:  "admin",
+		ComponentType: "address",
+	}}
+
+	if !reflect.DeepEqual(changes.Changes, want) {
+		t.Errorf("got %+v", changes.Changes)
+	}
+
+	if cmd := d.sent("op")[0].Get("cmd"); cmd != "<show><config><list><changes></changes></list></config></show>" {
+		t.Errorf("got %s", cmd)
+	}
+}
+
+func TestPendingChangesError(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="error" code="17"><msg><line>Invalid command</line></msg></response>`
+	})
+
+	if _, err := p.PendingChanges(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestRevertCandidate(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"10.1.0", "<revert><config></config></revert>"},
+		{"8.1.3", "<revert><config></config></revert>"},
+		{"8.0.9", "<load><config><from>running-config.xml</from></config></load>"},
+	}
+
+	for _, tt := range tests {
+		p, d := newFakeDevice(t, nil)
+		p.SoftwareVersion = tt.version
+
+		if err := p.RevertCandidate(); err != nil {
+			t.Fatalf("%s: %v", tt.version, err)
+		}
+
+		if cmd := d.sent("op")[0].Get("cmd"); cmd != tt.want {
+			t.Errorf("%s: got %s", tt.version, cmd)
+		}
+	}
+}
+
+func TestExportRunningConfig(t *testing.T) {
+	running := `<config version="10.1.0"><devices/></config>`
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return running
+	})
+
+	config, err := p.ExportRunningConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(config) != running {
+		t.Errorf("got %s", config)
+	}
+
+	if q := d.sent("export")[0]; q.Get("category") != "configuration" {
+		t.Errorf("unexpected query %v", q)
+	}
+
+	d.respond = func(q url.Values) string {
+		return `<response status="error"><msg><line>Export failed</line></msg></response>`
+	}
+
+	if _, err := p.ExportRunningConfig(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestExportCandidateConfig(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result>
+			<config version="10.1.0"><devices/></config>
+		</result></response>`
+	})
+
+	config, err := p.ExportCandidateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(config) != `<config version="10.1.0"><devices/></config>` {
+		t.Errorf("got %s", config)
+	}
+}
+
+func TestImportConfig(t *testing.T) {
+	var query url.Values
+	var filename, contents string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		b, _ := ioutil.ReadAll(file)
+		filename, contents = header.Filename, string(b)
+
+		w.Write([]byte(success))
+	}))
+	defer server.Close()
+
+	p := &PaloAlto{Key: "secret", URI: server.URL + "/api/?", client: server.Client()}
+
+	if err := p.ImportConfig("backup.xml", strings.NewReader("<config/>")); err != nil {
+		t.Fatal(err)
+	}
+
+	if query.Get("type") != "import" || query.Get("category") != "configuration" || query.Get("key") != "secret" {
+		t.Errorf("unexpected query %v", query)
+	}
+
+	if filename != "backup.xml" || contents != "<config/>" {
+		t.Errorf("uploaded %q as %q", contents, filename)
+	}
+
+	if err := p.ImportConfig("", strings.NewReader("<config/>")); err == nil {
+		t.Error("expected an error for a missing name")
+	}
+}
+
+func TestLoadSaveConfig(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	if err := p.SaveConfig("before <upgrade>.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.LoadConfig("backup.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := d.sent("op")
+	want := []string{
+		"<save><config><to>before &lt;upgrade&gt;.xml</to></config></save>",
+		"<load><config><from>backup.xml</from></config></load>",
+	}
+
+	for i, cmd := range want {
+		if sent[i].Get("cmd") != cmd {
+			t.Errorf("got %s, want %s", sent[i].Get("cmd"), cmd)
+		}
+	}
+}
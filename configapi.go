@@ -0,0 +1,282 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// ConfigGet returns the XML contents of the given xpath from the candidate configuration, which includes any changes
+// that have not been committed yet. Use this (along with the rest of the Config functions) to manage parts of the
+// configuration that the library doesn't have dedicated functions for.
+func (p *PaloAlto) ConfigGet(xpath string) ([]byte, error) {
+	return p.ConfigGetContext(context.Background(), xpath)
+}
+
+// ConfigGetContext is the same as ConfigGet, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigGetContext(ctx context.Context, xpath string) ([]byte, error) {
+	return p.configRead(ctx, "get", xpath)
+}
+
+// ConfigGetInto retrieves the given xpath from the candidate configuration, just like ConfigGet, and parses the
+// entire response into v. The struct should start at the <response> element, i.e. a field tagged
+// `xml:"result>entry"`.
+func (p *PaloAlto) ConfigGetInto(xpath string, v interface{}) error {
+	return p.ConfigGetIntoContext(context.Background(), xpath, v)
+}
+
+// ConfigGetIntoContext is the same as ConfigGetInto, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigGetIntoContext(ctx context.Context, xpath string, v interface{}) error {
+	return p.configReadInto(ctx, "get", xpath, v)
+}
+
+// ConfigShow returns the XML contents of the given xpath from the running (committed) configuration.
+func (p *PaloAlto) ConfigShow(xpath string) ([]byte, error) {
+	return p.ConfigShowContext(context.Background(), xpath)
+}
+
+// ConfigShowContext is the same as ConfigShow, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigShowContext(ctx context.Context, xpath string) ([]byte, error) {
+	return p.configRead(ctx, "show", xpath)
+}
+
+// ConfigShowInto retrieves the given xpath from the running configuration, just like ConfigShow, and parses the
+// entire response into v. The struct should start at the <response> element, i.e. a field tagged
+// `xml:"result>entry"`.
+func (p *PaloAlto) ConfigShowInto(xpath string, v interface{}) error {
+	return p.ConfigShowIntoContext(context.Background(), xpath, v)
+}
+
+// ConfigShowIntoContext is the same as ConfigShowInto, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigShowIntoContext(ctx context.Context, xpath string, v interface{}) error {
+	return p.configReadInto(ctx, "show", xpath, v)
+}
+
+// ConfigSet adds the given element to the configuration at xpath, merging it with anything that is already there.
+// The element can be a string or []byte of XML, or any value that encoding/xml can marshal (including an
+// xml.Marshaler), i.e. "<ip-netmask>10.1.1.1</ip-netmask>" for the xpath of an address object.
+func (p *PaloAlto) ConfigSet(xpath string, element interface{}) error {
+	return p.ConfigSetContext(context.Background(), xpath, element)
+}
+
+// ConfigSetContext is the same as ConfigSet, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigSetContext(ctx context.Context, xpath string, element interface{}) error {
+	return p.configWrite(ctx, "set", xpath, element, nil)
+}
+
+// ConfigEdit replaces the configuration at xpath with the given element. The element must be the node the xpath
+// points to, i.e. an <entry name="..."> element for an xpath ending in entry[@name='...'], and can be any of the
+// types accepted by ConfigSet.
+func (p *PaloAlto) ConfigEdit(xpath string, element interface{}) error {
+	return p.ConfigEditContext(context.Background(), xpath, element)
+}
+
+// ConfigEditContext is the same as ConfigEdit, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigEditContext(ctx context.Context, xpath string, element interface{}) error {
+	return p.configWrite(ctx, "edit", xpath, element, nil)
+}
+
+// ConfigDelete removes the configuration at xpath.
+func (p *PaloAlto) ConfigDelete(xpath string) error {
+	return p.ConfigDeleteContext(context.Background(), xpath)
+}
+
+// ConfigDeleteContext is the same as ConfigDelete, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigDeleteContext(ctx context.Context, xpath string) error {
+	return p.configWrite(ctx, "delete", xpath, nil, nil)
+}
+
+// ConfigRename renames the entry at xpath to newname. Any references to the entry, such as in groups or rules, are
+// updated as well.
+func (p *PaloAlto) ConfigRename(xpath, newname string) error {
+	return p.ConfigRenameContext(context.Background(), xpath, newname)
+}
+
+// ConfigRenameContext is the same as ConfigRename, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigRenameContext(ctx context.Context, xpath, newname string) error {
+	if newname == "" {
+		return errors.New("you must specify the new name of the entry")
+	}
+
+	return p.configWrite(ctx, "rename", xpath, nil, map[string]string{"newname": newname})
+}
+
+// ConfigMove moves the entry at xpath within its list, such as a rule within a rulebase. Where must be one of: top,
+// bottom, before or after, and dest is the name of the entry to move before or after.
+func (p *PaloAlto) ConfigMove(xpath, where, dest string) error {
+	return p.ConfigMoveContext(context.Background(), xpath, where, dest)
+}
+
+// ConfigMoveContext is the same as ConfigMove, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigMoveContext(ctx context.Context, xpath, where, dest string) error {
+	params := map[string]string{"where": where}
+
+	switch where {
+	case "top", "bottom":
+	case "before", "after":
+		if dest == "" {
+			return fmt.Errorf("you must specify a destination entry when moving an entry %s another", where)
+		}
+
+		params["dst"] = dest
+	default:
+		return errors.New("where must be one of: top, bottom, before or after")
+	}
+
+	return p.configWrite(ctx, "move", xpath, nil, params)
+}
+
+// ConfigClone copies the entry at from into the list at xpath, under newname. The xpath is the parent of the new
+// entry, i.e. ".../address", while from is the xpath of the entry to copy.
+func (p *PaloAlto) ConfigClone(xpath, from, newname string) error {
+	return p.ConfigCloneContext(context.Background(), xpath, from, newname)
+}
+
+// ConfigCloneContext is the same as ConfigClone, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigCloneContext(ctx context.Context, xpath, from, newname string) error {
+	if from == "" || newname == "" {
+		return errors.New("you must specify the entry to clone, and the name of the new entry")
+	}
+
+	return p.configWrite(ctx, "clone", xpath, nil, map[string]string{"from": from, "newname": newname})
+}
+
+// ConfigOverride overrides the configuration at xpath, which is pushed from a Panorama template, with the given
+// element on the firewall. The element can be any of the types accepted by ConfigSet.
+func (p *PaloAlto) ConfigOverride(xpath string, element interface{}) error {
+	return p.ConfigOverrideContext(context.Background(), xpath, element)
+}
+
+// ConfigOverrideContext is the same as ConfigOverride, but uses the given context for all API requests.
+func (p *PaloAlto) ConfigOverrideContext(ctx context.Context, xpath string, element interface{}) error {
+	return p.configWrite(ctx, "override", xpath, element, nil)
+}
+
+// configElement returns the given element as a string of XML. Strings and byte slices are used as-is, and anything
+// else is marshaled using encoding/xml.
+func configElement(element interface{}) (string, error) {
+	switch e := element.(type) {
+	case nil:
+		return "", errors.New("you must specify an element")
+	case string:
+		return e, nil
+	case []byte:
+		return string(e), nil
+	}
+
+	b, err := xml.Marshal(element)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// configRead sends a config "get" or "show" request for the given xpath, and returns the contents of the result.
+func (p *PaloAlto) configRead(ctx context.Context, action, xpath string) ([]byte, error) {
+	var result opResult
+
+	resp, query, err := p.configQuery(ctx, action, xpath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal(resp.Body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Status != "success" {
+		return nil, newAPIError(resp, query)
+	}
+
+	return bytes.TrimSpace(result.Result.Data), nil
+}
+
+// configReadInto sends a config "get" or "show" request for the given xpath, and parses the response into v.
+func (p *PaloAlto) configReadInto(ctx context.Context, action, xpath string, v interface{}) error {
+	var reqError requestError
+
+	resp, query, err := p.configQuery(ctx, action, xpath)
+	if err != nil {
+		return err
+	}
+
+	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+		return err
+	}
+
+	if reqError.Status != "success" {
+		return newAPIError(resp, query)
+	}
+
+	return xml.Unmarshal(resp.Body, v)
+}
+
+// configQuery sends a config "get" or "show" request for the given xpath, and returns the response along with the
+// query that was sent.
+func (p *PaloAlto) configQuery(ctx context.Context, action, xpath string) (*apiResponse, map[string]string, error) {
+	if xpath == "" {
+		return nil, nil, errors.New("you must specify an xpath")
+	}
+
+	query := map[string]string{
+		"type":   "config",
+		"action": action,
+		"xpath":  xpath,
+		"key":    p.Key,
+	}
+
+	resp := p.send(ctx, "get", query)
+	if resp.Error != nil {
+		return nil, nil, resp.Error
+	}
+
+	return resp, query, nil
+}
+
+// configWrite sends a config request that changes the configuration, such as "set" or "delete", for the given
+// xpath. The element (if not nil) and any extra parameters are added to the request.
+func (p *PaloAlto) configWrite(ctx context.Context, action, xpath string, element interface{}, params map[string]string) error {
+	var reqError requestError
+
+	if xpath == "" {
+		return errors.New("you must specify an xpath")
+	}
+
+	query := map[string]string{
+		"type":   "config",
+		"action": action,
+		"xpath":  xpath,
+		"key":    p.Key,
+	}
+
+	if action == "set" || action == "edit" || action == "override" {
+		e, err := configElement(element)
+		if err != nil {
+			return err
+		}
+
+		query["element"] = e
+	}
+
+	for k, v := range params {
+		query[k] = v
+	}
+
+	resp := p.send(ctx, "post", query)
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+		return err
+	}
+
+	if reqError.Status != "success" {
+		return newAPIError(resp, query)
+	}
+
+	return nil
+}
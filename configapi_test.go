@@ -0,0 +1,112 @@
+package panos
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestConfigGet(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result total-count="1" count="1">
+			<address><entry name="web"><fqdn>example.com</fqdn></entry></address>
+		</result></response>`
+	})
+
+	xpath := "/config/shared/address"
+
+	data, err := p.ConfigGet(xpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `<address><entry name="web"><fqdn>example.com</fqdn></entry></address>` {
+		t.Errorf("got %s", data)
+	}
+
+	var addrs struct {
+		FQDNs []string `xml:"result>address>entry>fqdn"`
+	}
+
+	if err := p.ConfigShowInto(xpath, &addrs); err != nil || len(addrs.FQDNs) != 1 || addrs.FQDNs[0] != "example.com" {
+		t.Errorf("got %+v, %v", addrs, err)
+	}
+
+	if q := d.sent("show")[0]; q.Get("xpath") != xpath || q.Get("type") != "config" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
+
+func TestConfigGetError(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="error" code="7"><msg><line>No such node</line></msg></response>`
+	})
+
+	if _, err := p.ConfigGet("/config/shared/address"); !IsNotFound(err) {
+		t.Errorf("err = %v, want a not found error", err)
+	}
+
+	if _, err := p.ConfigGet(""); err == nil {
+		t.Error("expected an error for an empty xpath")
+	}
+}
+
+func TestConfigWrite(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	entry := struct {
+		XMLName struct{} `xml:"entry"`
+		Name    string   `xml:"name,attr"`
+		FQDN    string   `xml:"fqdn"`
+	}{Name: "web", FQDN: "a&b"}
+
+	if err := p.ConfigSet("/config/shared/address", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.ConfigEdit("/config/shared/address/entry[@name='web']", "<entry name='web'/>"); err != nil {
+		t.Fatal(err)
+	}
+
+	if element := d.sent("set")[0].Get("element"); element != `<entry name="web"><fqdn>a&amp;b</fqdn></entry>` {
+		t.Errorf("got %s", element)
+	}
+
+	if element := d.sent("edit")[0].Get("element"); element != "<entry name='web'/>" {
+		t.Errorf("got %s", element)
+	}
+
+	if err := p.ConfigMove("/config/x", "after", "rule1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if q := d.sent("move")[0]; q.Get("where") != "after" || q.Get("dst") != "rule1" {
+		t.Errorf("unexpected query %v", q)
+	}
+
+	if err := p.ConfigDelete("/config/x"); err != nil || d.sent("delete")[0].Get("element") != "" {
+		t.Errorf("unexpected delete: %v", err)
+	}
+}
+
+func TestConfigWriteInvalid(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	errs := []error{
+		p.ConfigSet("/config/x", nil),
+		p.ConfigSet("", "<a/>"),
+		p.ConfigMove("/config/x", "before", ""),
+		p.ConfigMove("/config/x", "middle", ""),
+		p.ConfigRename("/config/x", ""),
+		p.ConfigClone("/config/x", "", "y"),
+	}
+
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+
+	if len(d.sent("")) != 0 {
+		t.Error("invalid requests should not be sent")
+	}
+}
@@ -0,0 +1,160 @@
+package panos
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// APIError is returned whenever the device responds to a request with an error. You can inspect it using
+// errors.As, or use one of the helpers such as IsNotFound.
+type APIError struct {
+	// Code is the error code returned by the device, i.e. "7".
+	Code string
+	// Messages contains each line of the error message returned by the device, if any.
+	Messages []string
+	// Type is the type of API request that failed, i.e. "config", "op" or "commit".
+	Type string
+	// Action is the action of the request that failed, i.e. "set" or "delete".
+	Action string
+	// XPath is the xpath the failed request was operating on.
+	XPath string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+}
+
+// UserIDError is returned when the device rejects one or more entries of a User-ID update, such as a login or
+// tag registration. The rest of the entries were still applied.
+type UserIDError struct {
+	Failures []UserIDFailure
+}
+
+// BatchError is returned when an operation in a Batch fails. Index is the position of the failed operation in the
+// batch, and Applied is the number of operations (from the start of the batch) that were applied before it. Err is
+// the underlying error, which is usually an *APIError.
+type BatchError struct {
+	Index   int
+	Action  string
+	XPath   string
+	Applied int
+	Err     error
+}
+
+// errorMessage is used for parsing the message of an error response, which is either plain text or
+// made up of multiple <line> elements.
+type errorMessage struct {
+	Text  string   `xml:",chardata"`
+	Lines []string `xml:"line"`
+}
+
+// Error returns the error code, its description and any message the device returned.
+func (e *APIError) Error() string {
+	msg := strings.Join(e.Messages, " ")
+
+	if e.Code == "" {
+		if msg == "" {
+			return fmt.Sprintf("request failed with HTTP status %d", e.StatusCode)
+		}
+
+		return fmt.Sprintf("request failed: %s", msg)
+	}
+
+	if msg == "" {
+		return fmt.Sprintf("error code %s: %s", e.Code, errorCodes[e.Code])
+	}
+
+	return fmt.Sprintf("error code %s: %s: %s", e.Code, errorCodes[e.Code], msg)
+}
+
+// Error returns the number of entries that failed, along with the message for each of them.
+func (e *UserIDError) Error() string {
+	var msgs []string
+
+	for _, f := range e.Failures {
+		entry := f.IP
+		if f.Name != "" {
+			entry = strings.TrimSpace(f.Name + " " + f.IP)
+		}
+
+		msgs = append(msgs, fmt.Sprintf("%s %s: %s", f.Type, entry, f.Message))
+	}
+
+	return fmt.Sprintf("%d User-ID entries failed: %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// Error returns the position of the failed operation in the batch, along with its error.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch operation %d (%s %s) failed: %v", e.Index, e.Action, e.XPath, e.Err)
+}
+
+// Unwrap returns the underlying error, so that helpers such as IsNotFound can be used on a *BatchError.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound returns true if the error was caused by the object specified in the xpath not being present (code 7).
+func IsNotFound(err error) bool {
+	return hasErrorCode(err, "7")
+}
+
+// IsReferenced returns true if the error was caused by trying to delete an object that is still referenced
+// by other objects, such as a policy or group (code 10).
+func IsReferenced(err error) bool {
+	return hasErrorCode(err, "10")
+}
+
+// IsUnauthorized returns true if the error was caused by an invalid API key or insufficient access
+// rights (code 403 or 16).
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.Code == "403" || apiErr.Code == "16" || apiErr.StatusCode == 403
+}
+
+// hasErrorCode returns true if err is an *APIError with the given code.
+func hasErrorCode(err error, code string) bool {
+	var apiErr *APIError
+
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.Code == code
+}
+
+// newAPIError builds an *APIError from a failed response, along with the query that was sent.
+func newAPIError(resp *apiResponse, query map[string]string) *APIError {
+	var reqError requestError
+
+	apiErr := &APIError{
+		Type:       query["type"],
+		Action:     query["action"],
+		XPath:      query["xpath"],
+		StatusCode: resp.Status,
+	}
+
+	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+		return apiErr
+	}
+
+	apiErr.Code = reqError.Code
+
+	for _, m := range []errorMessage{reqError.Message, reqError.ResultMessage} {
+		for _, line := range m.Lines {
+			if l := strings.TrimSpace(line); l != "" {
+				apiErr.Messages = append(apiErr.Messages, l)
+			}
+		}
+
+		if t := strings.TrimSpace(m.Text); t != "" {
+			apiErr.Messages = append(apiErr.Messages, t)
+		}
+	}
+
+	return apiErr
+}
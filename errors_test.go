@@ -0,0 +1,98 @@
+package panos
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     string
+		messages []string
+	}{
+		{
+			name:     "lines",
+			body:     `<response status="error" code="12"><msg><line><![CDATA[ test -> address is invalid]]></line><line>second</line></msg></response>`,
+			code:     "12",
+			messages: []string{"test -> address is invalid", "second"},
+		},
+		{
+			name:     "result message",
+			body:     `<response status="error" code="7"><result><msg>Object doesn't exist</msg></result></response>`,
+			code:     "7",
+			messages: []string{"Object doesn't exist"},
+		},
+		{
+			name: "not xml",
+			body: `<html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		resp := &apiResponse{Body: []byte(tt.body), Status: 200}
+		err := newAPIError(resp, map[string]string{"type": "config", "action": "get", "xpath": "/config/shared"})
+
+		if err.Code != tt.code || !reflect.DeepEqual(err.Messages, tt.messages) {
+			t.Errorf("%s: got code %q and messages %q", tt.name, err.Code, err.Messages)
+		}
+
+		if err.Type != "config" || err.Action != "get" || err.XPath != "/config/shared" || err.StatusCode != 200 {
+			t.Errorf("%s: request details not set: %+v", tt.name, err)
+		}
+	}
+}
+
+func TestAPIErrorString(t *testing.T) {
+	tests := []struct {
+		err  *APIError
+		want string
+	}{
+		{&APIError{StatusCode: 500}, "request failed with HTTP status 500"},
+		{&APIError{Messages: []string{"bad"}}, "request failed: bad"},
+		{&APIError{Code: "7"}, "error code 7: " + errorCodes["7"]},
+		{&APIError{Code: "10", Messages: []string{"a", "b"}}, "error code 10: " + errorCodes["10"] + ": a b"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	wrapped := fmt.Errorf("deleting: %w", &APIError{Code: "10"})
+
+	if !IsReferenced(wrapped) || IsNotFound(wrapped) {
+		t.Error("IsReferenced should see through wrapped errors")
+	}
+
+	if !IsNotFound(&BatchError{Err: &APIError{Code: "7"}}) {
+		t.Error("IsNotFound should see through a *BatchError")
+	}
+
+	for _, err := range []error{&APIError{Code: "403"}, &APIError{Code: "16"}, &APIError{StatusCode: 403}} {
+		if !IsUnauthorized(err) {
+			t.Errorf("IsUnauthorized(%v) = false", err)
+		}
+	}
+
+	if IsNotFound(fmt.Errorf("plain")) {
+		t.Error("a plain error is not an API error")
+	}
+}
+
+func TestAPIErrorFromDevice(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="error" code="7"><msg>No such node</msg></response>`
+	})
+
+	_, err := p.ConfigGet("/config/shared/address/entry[@name='missing']")
+	if !IsNotFound(err) {
+		t.Fatalf("err = %v, want a not found error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package panos
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxNameLength is the longest name PAN-OS allows for objects, groups, rules and most other entries.
+	maxNameLength = 63
+	// maxTagNameLength is the longest name PAN-OS allows for tags.
+	maxTagNameLength = 127
+)
+
+// escapeXML escapes the given text so that it can be used as the content of an element, or the value of an
+// attribute, in an XML document sent to the device.
+func escapeXML(s string) string {
+	var b bytes.Buffer
+
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
+
+// EscapeXML escapes the given text so that it can be used in an element you build yourself, such as one given to
+// ConfigSet or Batch.Set, i.e. fmt.Sprintf("<description>%s</description>", panos.EscapeXML(desc)).
+func EscapeXML(s string) string {
+	return escapeXML(s)
+}
+
+// xpathLiteral returns the given value as a quoted xpath string literal, i.e. for use in [@name=...] or
+// [text()=...]. Values containing both single and double quotes are built using concat().
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+
+	parts := strings.Split(s, "'")
+	lit := make([]string, 0, len(parts)*2)
+
+	for i, part := range parts {
+		if i > 0 {
+			lit = append(lit, `"'"`)
+		}
+
+		if part != "" {
+			lit = append(lit, "'"+part+"'")
+		}
+	}
+
+	return "concat(" + strings.Join(lit, ", ") + ")"
+}
+
+// validateName checks that the given name follows the PAN-OS naming rules for objects, groups and rules: it must be
+// 1-63 characters long, start with a letter, number or underscore, and only contain letters, numbers, spaces,
+// hyphens, periods and underscores.
+func validateName(name string) error {
+	if name == "" {
+		return errors.New("you must specify a name")
+	}
+
+	if len(name) > maxNameLength {
+		return fmt.Errorf("invalid name %q: must be %d characters or less", name, maxNameLength)
+	}
+
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+		case i > 0 && (c == ' ' || c == '-' || c == '.'):
+		case i == 0:
+			return fmt.Errorf("invalid name %q: must start with a letter, number or underscore", name)
+		default:
+			return fmt.Errorf("invalid name %q: can only contain letters, numbers, spaces, hyphens, periods and underscores", name)
+		}
+	}
+
+	return nil
+}
+
+// validateTagName checks that the given tag name follows the PAN-OS naming rules for tags, which allow any
+// printable character, but must be 1-127 characters long.
+func validateTagName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("you must specify a name")
+	}
+
+	if len(name) > maxTagNameLength {
+		return fmt.Errorf("invalid tag name %q: must be %d characters or less", name, maxTagNameLength)
+	}
+
+	for _, c := range name {
+		if c < ' ' || c == 0x7f {
+			return fmt.Errorf("invalid tag name %q: can not contain control characters", name)
+		}
+	}
+
+	return nil
+}
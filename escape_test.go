@@ -0,0 +1,60 @@
+package panos
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeXML(t *testing.T) {
+	if got := escapeXML(`<a href="x">&'</a>`); got != "&lt;a href=&#34;x&#34;&gt;&amp;&#39;&lt;/a&gt;" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestXpathLiteral(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"web", "'web'"},
+		{"", "''"},
+		{"bob's", `"bob's"`},
+		{`say "hi"`, `'say "hi"'`},
+		{`it's "x"`, `concat('it', "'", 's "x"')`},
+		{`'"'`, `concat("'", '"', "'")`},
+	}
+
+	for _, tt := range tests {
+		if got := xpathLiteral(tt.in); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"web", "_web", "1web", "web server-1.prod_a", strings.Repeat("a", 63)} {
+		if err := validateName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"", " web", "-web", "web/1", "web'", strings.Repeat("a", 64)} {
+		if err := validateName(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+func TestValidateTagName(t *testing.T) {
+	for _, name := range []string{"prod", "a/b & 'c'", strings.Repeat("a", 127)} {
+		if err := validateTagName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"", "  ", "a\tb", strings.Repeat("a", 128)} {
+		if err := validateTagName(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,822 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImportReport contains the result of each record of an import, in the same order as the input.
+type ImportReport struct {
+	Results []ImportResult
+}
+
+// ImportResult contains the result of importing an individual record. Row is the position of the record in the
+// input, starting at 1 (not counting the header of a CSV file). Action is one of: created or updated, and Err is set
+// if the record could not be imported.
+type ImportResult struct {
+	Row    int
+	Name   string
+	Action string
+	Err    error
+}
+
+// addressRecord is used for exporting and importing each address object.
+type addressRecord struct {
+	Name        string   `json:"name" yaml:"name"`
+	Type        string   `json:"type" yaml:"type"`
+	Value       string   `json:"value" yaml:"value"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// addressGroupRecord is used for exporting and importing each address group.
+type addressGroupRecord struct {
+	Name        string   `json:"name" yaml:"name"`
+	Type        string   `json:"type" yaml:"type"`
+	Members     []string `json:"members,omitempty" yaml:"members,omitempty"`
+	Filter      string   `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// serviceRecord is used for exporting and importing each service object.
+type serviceRecord struct {
+	Name        string   `json:"name" yaml:"name"`
+	Protocol    string   `json:"protocol" yaml:"protocol"`
+	Port        string   `json:"port" yaml:"port"`
+	SourcePort  string   `json:"source_port,omitempty" yaml:"source_port,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// serviceGroupRecord is used for exporting and importing each service group.
+type serviceGroupRecord struct {
+	Name        string   `json:"name" yaml:"name"`
+	Members     []string `json:"members" yaml:"members"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// tagRecord is used for exporting and importing each tag.
+type tagRecord struct {
+	Name     string `json:"name" yaml:"name"`
+	Color    string `json:"color,omitempty" yaml:"color,omitempty"`
+	Comments string `json:"comments,omitempty" yaml:"comments,omitempty"`
+}
+
+// xmlAddressGroupEntry is used for creating or editing an address group.
+type xmlAddressGroupEntry struct {
+	XMLName     xml.Name         `xml:"entry"`
+	Name        string           `xml:"name,attr"`
+	Static      *xmlMembers      `xml:"static,omitempty"`
+	Dynamic     *xmlDynamicGroup `xml:"dynamic,omitempty"`
+	Description string           `xml:"description,omitempty"`
+}
+
+// xmlDynamicGroup is used for the filter of a dynamic address group, which is left out entirely for static groups.
+type xmlDynamicGroup struct {
+	Filter string `xml:"filter"`
+}
+
+// xmlServiceGroupEntry is used for creating or editing a service group.
+type xmlServiceGroupEntry struct {
+	XMLName     xml.Name    `xml:"entry"`
+	Name        string      `xml:"name,attr"`
+	Members     *xmlMembers `xml:"members,omitempty"`
+	Description string      `xml:"description,omitempty"`
+}
+
+// xmlTagEntry is used for creating or editing a tag.
+type xmlTagEntry struct {
+	XMLName  xml.Name `xml:"entry"`
+	Name     string   `xml:"name,attr"`
+	Color    string   `xml:"color,omitempty"`
+	Comments string   `xml:"comments,omitempty"`
+}
+
+// xmlEntryNames is used for parsing the names of all entries in part of the configuration.
+type xmlEntryNames struct {
+	Entries []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"entry"`
+}
+
+// xmlImportEntries is used for parsing the existing entries of an object type when importing.
+type xmlImportEntries struct {
+	Entries []xmlImportEntry `xml:"entry"`
+}
+
+// xmlImportEntry is used for parsing the name and the raw contents of each existing entry.
+type xmlImportEntry struct {
+	Name  string `xml:"name,attr"`
+	Inner []byte `xml:",innerxml"`
+}
+
+// importNodes contains the settings (by their path within the entry) that the records of each object type have,
+// which are replaced when an existing entry is updated. A setting is listed before any that are inside of it.
+var importNodes = map[string][]string{
+	"address":       {"ip-netmask", "ip-range", "ip-wildcard", "fqdn", "description", "tag"},
+	"address-group": {"static", "dynamic", "description"},
+	"service": {"protocol/tcp", "protocol/udp", "protocol/sctp", "protocol/tcp/source-port", "protocol/udp/source-port",
+		"protocol/sctp/source-port", "description", "tag"},
+	"service-group": {"members", "description"},
+	"tag":           {"color", "comments"},
+}
+
+// Failed returns the results of the records that could not be imported.
+func (r *ImportReport) Failed() []ImportResult {
+	var failed []ImportResult
+
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+
+	return failed
+}
+
+// Export writes all of the address objects to w in the given format, which should be one of: csv, json or yaml.
+// Each object has the fields: name, type (ip, range, wildcard or fqdn), value, description and tags. In a CSV file,
+// multiple tags are separated by a semicolon.
+func (a *AddressObjects) Export(w io.Writer, format string) error {
+	records := []addressRecord{}
+
+	for _, addr := range a.Addresses {
+		r := addressRecord{Name: addr.Name, Type: addr.Type(), Description: addr.Description, Tags: addr.Tags}
+
+		switch r.Type {
+		case "ip":
+			r.Value = addr.IPAddress
+		case "range":
+			r.Value = addr.IPRange
+		case "wildcard":
+			r.Value = addr.IPWildcard
+		case "fqdn":
+			r.Value = addr.FQDN
+		}
+
+		records = append(records, r)
+	}
+
+	header := []string{"name", "type", "value", "description", "tags"}
+
+	return writeRecords(w, format, records, header, len(records), func(i int) []string {
+		r := records[i]
+		return []string{r.Name, r.Type, r.Value, r.Description, joinList(r.Tags)}
+	})
+}
+
+// Export writes all of the address groups to w in the given format, which should be one of: csv, json or yaml.
+// Each group has the fields: name, type (static or dynamic), members, filter and description. In a CSV file,
+// multiple members are separated by a semicolon.
+func (g *AddressGroups) Export(w io.Writer, format string) error {
+	records := []addressGroupRecord{}
+
+	for _, group := range g.Groups {
+		records = append(records, addressGroupRecord{
+			Name:        group.Name,
+			Type:        strings.ToLower(group.Type),
+			Members:     group.Members,
+			Filter:      group.DynamicFilter,
+			Description: group.Description,
+		})
+	}
+
+	header := []string{"name", "type", "members", "filter", "description"}
+
+	return writeRecords(w, format, records, header, len(records), func(i int) []string {
+		r := records[i]
+		return []string{r.Name, r.Type, joinList(r.Members), r.Filter, r.Description}
+	})
+}
+
+// Export writes all of the service objects to w in the given format, which should be one of: csv, json or yaml.
+// Each object has the fields: name, protocol (tcp, udp or sctp), port, source_port, description and tags. In a CSV
+// file, multiple tags are separated by a semicolon. Timeout overrides are not exported.
+func (s *ServiceObjects) Export(w io.Writer, format string) error {
+	records := []serviceRecord{}
+
+	for _, svc := range s.Services {
+		r := serviceRecord{
+			Name:        svc.Name,
+			Protocol:    svc.Protocol(),
+			SourcePort:  svc.SourcePort,
+			Description: svc.Description,
+			Tags:        svc.Tags,
+		}
+
+		switch r.Protocol {
+		case "tcp":
+			r.Port = svc.TCPPort
+		case "udp":
+			r.Port = svc.UDPPort
+		case "sctp":
+			r.Port = svc.SCTPPort
+		}
+
+		records = append(records, r)
+	}
+
+	header := []string{"name", "protocol", "port", "source_port", "description", "tags"}
+
+	return writeRecords(w, format, records, header, len(records), func(i int) []string {
+		r := records[i]
+		return []string{r.Name, r.Protocol, r.Port, r.SourcePort, r.Description, joinList(r.Tags)}
+	})
+}
+
+// Export writes all of the service groups to w in the given format, which should be one of: csv, json or yaml.
+// Each group has the fields: name, members and description. In a CSV file, multiple members are separated by a
+// semicolon.
+func (g *ServiceGroups) Export(w io.Writer, format string) error {
+	records := []serviceGroupRecord{}
+
+	for _, group := range g.Groups {
+		records = append(records, serviceGroupRecord{Name: group.Name, Members: group.Members, Description: group.Description})
+	}
+
+	header := []string{"name", "members", "description"}
+
+	return writeRecords(w, format, records, header, len(records), func(i int) []string {
+		r := records[i]
+		return []string{r.Name, joinList(r.Members), r.Description}
+	})
+}
+
+// Export writes all of the tags to w in the given format, which should be one of: csv, json or yaml. Each tag has
+// the fields: name, color (i.e. "Red") and comments.
+func (t *Tags) Export(w io.Writer, format string) error {
+	records := []tagRecord{}
+
+	for _, tag := range t.Tags {
+		records = append(records, tagRecord{Name: tag.Name, Color: tag.Color, Comments: tag.Comments})
+	}
+
+	header := []string{"name", "color", "comments"}
+
+	return writeRecords(w, format, records, header, len(records), func(i int) []string {
+		r := records[i]
+		return []string{r.Name, r.Color, r.Comments}
+	})
+}
+
+// ImportAddresses reads address objects from r, in the same format written by AddressObjects.Export, and creates
+// them on the device. Objects that already exist are updated to match each record: its type, value, description
+// and tags replace the existing ones, while any settings a record doesn't have, such as disable-override, are kept
+// as-is. When importing on a Panorama device, specify the location (i.e. a DeviceGroupLocation) as the last
+// parameter. On a multi-vsys firewall, you can specify a VsysLocation instead. An error is only returned if the input
+// could not be read - the result of each record is in the report.
+func (p *PaloAlto) ImportAddresses(r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	return p.ImportAddressesContext(context.Background(), r, format, loc...)
+}
+
+// ImportAddressesContext is the same as ImportAddresses, but uses the given context for all API requests.
+func (p *PaloAlto) ImportAddressesContext(ctx context.Context, r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	var records []addressRecord
+
+	err := readRecords(r, format, &records, func(row map[string]string) {
+		records = append(records, addressRecord{
+			Name:        row["name"],
+			Type:        row["type"],
+			Value:       row["value"],
+			Description: row["description"],
+			Tags:        splitList(row["tags"]),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]interface{}, len(records))
+	for i, rec := range records {
+		addr := &Address{Name: rec.Name, Description: rec.Description, Tags: rec.Tags}
+
+		switch rec.Type {
+		case "ip":
+			addr.IPAddress = rec.Value
+		case "range":
+			addr.IPRange = rec.Value
+		case "wildcard":
+			addr.IPWildcard = rec.Value
+		case "fqdn":
+			addr.FQDN = rec.Value
+		default:
+			entries[i] = fmt.Errorf("unknown address type %s, should be one of: ip, range, wildcard or fqdn", rec.Type)
+			continue
+		}
+
+		x, err := addr.toXML()
+		if err != nil {
+			entries[i] = err
+			continue
+		}
+
+		entries[i] = x
+	}
+
+	return p.importEntries(ctx, "address", recordNames(len(records), func(i int) string { return records[i].Name }), entries, loc)
+}
+
+// ImportAddressGroups reads address groups from r, in the same format written by AddressGroups.Export, and creates
+// them on the device. Groups that already exist are updated the same way as ImportAddresses, so the type, members
+// and description of each record replace the existing ones. The location is specified the same way as
+// ImportAddresses.
+func (p *PaloAlto) ImportAddressGroups(r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	return p.ImportAddressGroupsContext(context.Background(), r, format, loc...)
+}
+
+// ImportAddressGroupsContext is the same as ImportAddressGroups, but uses the given context for all API requests.
+func (p *PaloAlto) ImportAddressGroupsContext(ctx context.Context, r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	var records []addressGroupRecord
+
+	err := readRecords(r, format, &records, func(row map[string]string) {
+		records = append(records, addressGroupRecord{
+			Name:        row["name"],
+			Type:        row["type"],
+			Members:     splitList(row["members"]),
+			Filter:      row["filter"],
+			Description: row["description"],
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]interface{}, len(records))
+	for i, rec := range records {
+		x := &xmlAddressGroupEntry{Name: rec.Name, Description: rec.Description}
+
+		switch strings.ToLower(rec.Type) {
+		case "static":
+			x.Static = newMembers(rec.Members...)
+			if x.Static == nil {
+				entries[i] = fmt.Errorf("static address group %s must have at least one member", rec.Name)
+				continue
+			}
+		case "dynamic":
+			if rec.Filter == "" {
+				entries[i] = fmt.Errorf("dynamic address group %s must have a filter", rec.Name)
+				continue
+			}
+
+			x.Dynamic = &xmlDynamicGroup{Filter: rec.Filter}
+		default:
+			entries[i] = fmt.Errorf("unknown address group type %s, should be one of: static or dynamic", rec.Type)
+			continue
+		}
+
+		entries[i] = x
+	}
+
+	return p.importEntries(ctx, "address-group", recordNames(len(records), func(i int) string { return records[i].Name }), entries, loc)
+}
+
+// ImportServices reads service objects from r, in the same format written by ServiceObjects.Export, and creates
+// them on the device. Objects that already exist are updated the same way as ImportAddresses, so settings such as
+// the session timeout overrides are kept, unless the protocol changes. The location is specified the same way as
+// ImportAddresses.
+func (p *PaloAlto) ImportServices(r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	return p.ImportServicesContext(context.Background(), r, format, loc...)
+}
+
+// ImportServicesContext is the same as ImportServices, but uses the given context for all API requests.
+func (p *PaloAlto) ImportServicesContext(ctx context.Context, r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	var records []serviceRecord
+
+	err := readRecords(r, format, &records, func(row map[string]string) {
+		records = append(records, serviceRecord{
+			Name:        row["name"],
+			Protocol:    row["protocol"],
+			Port:        row["port"],
+			SourcePort:  row["source_port"],
+			Description: row["description"],
+			Tags:        splitList(row["tags"]),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]interface{}, len(records))
+	for i, rec := range records {
+		svc := &Service{Name: rec.Name, SourcePort: rec.SourcePort, Description: rec.Description, Tags: rec.Tags}
+
+		switch rec.Protocol {
+		case "tcp":
+			svc.TCPPort = rec.Port
+		case "udp":
+			svc.UDPPort = rec.Port
+		case "sctp":
+			svc.SCTPPort = rec.Port
+		default:
+			entries[i] = fmt.Errorf("unknown protocol %s, should be one of: tcp, udp or sctp", rec.Protocol)
+			continue
+		}
+
+		x, err := svc.toXML()
+		if err != nil {
+			entries[i] = err
+			continue
+		}
+
+		entries[i] = x
+	}
+
+	return p.importEntries(ctx, "service", recordNames(len(records), func(i int) string { return records[i].Name }), entries, loc)
+}
+
+// ImportServiceGroups reads service groups from r, in the same format written by ServiceGroups.Export, and creates
+// them on the device. Groups that already exist are updated the same way as ImportAddresses, so the members and
+// description of each record replace the existing ones. The location is specified the same way as ImportAddresses.
+func (p *PaloAlto) ImportServiceGroups(r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	return p.ImportServiceGroupsContext(context.Background(), r, format, loc...)
+}
+
+// ImportServiceGroupsContext is the same as ImportServiceGroups, but uses the given context for all API requests.
+func (p *PaloAlto) ImportServiceGroupsContext(ctx context.Context, r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	var records []serviceGroupRecord
+
+	err := readRecords(r, format, &records, func(row map[string]string) {
+		records = append(records, serviceGroupRecord{
+			Name:        row["name"],
+			Members:     splitList(row["members"]),
+			Description: row["description"],
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]interface{}, len(records))
+	for i, rec := range records {
+		x := &xmlServiceGroupEntry{Name: rec.Name, Members: newMembers(rec.Members...), Description: rec.Description}
+
+		if x.Members == nil {
+			entries[i] = fmt.Errorf("service group %s must have at least one member", rec.Name)
+			continue
+		}
+
+		entries[i] = x
+	}
+
+	return p.importEntries(ctx, "service-group", recordNames(len(records), func(i int) string { return records[i].Name }), entries, loc)
+}
+
+// ImportTags reads tags from r, in the same format written by Tags.Export, and creates them on the device. Tags that
+// already exist are updated, the same way as ImportAddresses. The color should be one of the colors accepted by
+// CreateTag. The location is specified the same way as ImportAddresses.
+func (p *PaloAlto) ImportTags(r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	return p.ImportTagsContext(context.Background(), r, format, loc...)
+}
+
+// ImportTagsContext is the same as ImportTags, but uses the given context for all API requests.
+func (p *PaloAlto) ImportTagsContext(ctx context.Context, r io.Reader, format string, loc ...Location) (*ImportReport, error) {
+	var records []tagRecord
+
+	err := readRecords(r, format, &records, func(row map[string]string) {
+		records = append(records, tagRecord{Name: row["name"], Color: row["color"], Comments: row["comments"]})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]interface{}, len(records))
+	for i, rec := range records {
+		color, ok := tagColors[rec.Color]
+		if rec.Color != "" && !ok {
+			entries[i] = fmt.Errorf("unknown tag color %s", rec.Color)
+			continue
+		}
+
+		entries[i] = &xmlTagEntry{Name: rec.Name, Color: color, Comments: rec.Comments}
+	}
+
+	return p.importEntries(ctx, "tag", recordNames(len(records), func(i int) string { return records[i].Name }), entries, loc)
+}
+
+// importEntries creates or updates each entry of the given object type (i.e. "address") in the location. An entry
+// that is an error is reported as failed without being sent.
+func (p *PaloAlto) importEntries(ctx context.Context, objecttype string, names []string, entries []interface{}, loc []Location) (*ImportReport, error) {
+	var report ImportReport
+	var parsed xmlImportEntries
+	validate := validateName
+
+	if objecttype == "tag" {
+		validate = validateTagName
+	}
+
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	xpath := fmt.Sprintf("%s/%s", base, objecttype)
+
+	data, err := p.ConfigGetContext(ctx, xpath)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := xml.Unmarshal(data, &parsed); err != nil {
+			return nil, err
+		}
+	}
+
+	existing := map[string]map[string][]string{}
+	for _, e := range parsed.Entries {
+		nodes, err := entryNodes(e.Inner)
+		if err != nil {
+			return nil, err
+		}
+
+		existing[e.Name] = nodes
+	}
+
+	for i, entry := range entries {
+		var nodes map[string][]string
+
+		res := ImportResult{Row: i + 1, Name: names[i], Action: "created"}
+		old, exists := existing[names[i]]
+
+		if exists {
+			res.Action = "updated"
+		}
+
+		err, _ := entry.(error)
+		if err == nil {
+			err = validate(names[i])
+		}
+
+		if err == nil {
+			nodes, err = elementNodes(entry)
+		}
+
+		if err == nil && exists {
+			replaced, removed := importChanges(importNodes[objecttype], old, nodes)
+			err = p.importEdit(ctx, xpath, names[i], entry, replaced, removed)
+		} else if err == nil {
+			err = p.setEntry(ctx, "set", xpath, names[i], entry)
+		}
+
+		if err == nil {
+			existing[names[i]] = nodes
+		}
+
+		res.Err = err
+		report.Results = append(report.Results, res)
+	}
+
+	return &report, nil
+}
+
+// importEdit updates an existing entry with an imported one. The entry is merged into the existing one, so that any
+// settings the records don't have are kept. Replaced settings, such as an ip-netmask replaced by an fqdn or a cleared
+// description, are deleted first, and members that are no longer wanted are deleted afterwards.
+func (p *PaloAlto) importEdit(ctx context.Context, xpath, name string, entry interface{}, replaced, removed []string) error {
+	path := fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(name))
+
+	for _, node := range replaced {
+		if err := p.ConfigDeleteContext(ctx, path+"/"+node); err != nil {
+			return err
+		}
+	}
+
+	if err := p.setEntry(ctx, "set", xpath, name, entry); err != nil {
+		return err
+	}
+
+	for _, node := range removed {
+		if err := p.ConfigDeleteContext(ctx, path+"/"+node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importChanges compares the given settings of an existing entry against an imported one. It returns the settings
+// the imported entry doesn't have, and the members of its lists that it no longer has.
+func importChanges(managed []string, old, nodes map[string][]string) ([]string, []string) {
+	var replaced, removed []string
+
+	for _, node := range managed {
+		members, ok := old[node]
+		if !ok || insideAny(node, replaced) {
+			continue
+		}
+
+		wanted, ok := nodes[node]
+		if !ok {
+			replaced = append(replaced, node)
+			continue
+		}
+
+		keep := map[string]bool{}
+		for _, m := range wanted {
+			keep[m] = true
+		}
+
+		for _, m := range members {
+			if !keep[m] {
+				removed = append(removed, fmt.Sprintf("%s/member[text()=%s]", node, xpathLiteral(m)))
+			}
+		}
+	}
+
+	return replaced, removed
+}
+
+// insideAny returns true if the setting at node is inside of any of the given settings.
+func insideAny(node string, parents []string) bool {
+	for _, parent := range parents {
+		if strings.HasPrefix(node, parent+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// elementNodes returns the settings of the given entry, the same way as entryNodes.
+func elementNodes(entry interface{}) (map[string][]string, error) {
+	var x xmlImportEntry
+
+	data, err := xml.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+
+	return entryNodes(x.Inner)
+}
+
+// entryNodes parses the contents of an entry into each of its settings, by their path within the entry (i.e.
+// "protocol/tcp/port"), along with the members of the settings that are lists.
+func entryNodes(inner []byte) (map[string][]string, error) {
+	var path []string
+	var text strings.Builder
+
+	nodes := map[string][]string{}
+	d := xml.NewDecoder(bytes.NewReader(inner))
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nodes, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "member" {
+				path = append(path, t.Name.Local)
+				nodes[strings.Join(path, "/")] = nil
+			}
+
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if t.Name.Local == "member" {
+				node := strings.Join(path, "/")
+				nodes[node] = append(nodes[node], strings.TrimSpace(text.String()))
+				continue
+			}
+
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+// writeRecords writes the records to w in the given format. For CSV, the header is written first, followed by the
+// row returned by the row function for each of the n records.
+func writeRecords(w io.Writer, format string, records interface{}, header []string, n int, row func(i int) []string) error {
+	switch strings.ToLower(format) {
+	case "csv":
+		cw := csv.NewWriter(w)
+
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+
+		for i := 0; i < n; i++ {
+			if err := cw.Write(row(i)); err != nil {
+				return err
+			}
+		}
+
+		cw.Flush()
+
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(records)
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+
+		if err := enc.Encode(records); err != nil {
+			return err
+		}
+
+		return enc.Close()
+	}
+
+	return fmt.Errorf("unknown format %s, should be one of: csv, json or yaml", format)
+}
+
+// readRecords reads records from r in the given format. JSON and YAML are decoded into records, which must be a
+// pointer to a slice, while each row of a CSV file is passed to the fromCSV function, keyed by its (lowercase)
+// header.
+func readRecords(r io.Reader, format string, records interface{}, fromCSV func(row map[string]string)) error {
+	switch strings.ToLower(format) {
+	case "csv":
+		cr := csv.NewReader(r)
+		cr.TrimLeadingSpace = true
+
+		rows, err := cr.ReadAll()
+		if err != nil {
+			return err
+		}
+
+		if len(rows) <= 0 {
+			return nil
+		}
+
+		header := rows[0]
+		for i, h := range header {
+			header[i] = strings.ToLower(strings.TrimSpace(h))
+		}
+
+		for _, values := range rows[1:] {
+			row := map[string]string{}
+
+			for i, v := range values {
+				if i < len(header) {
+					row[header[i]] = strings.TrimSpace(v)
+				}
+			}
+
+			fromCSV(row)
+		}
+
+		return nil
+	case "json":
+		return json.NewDecoder(r).Decode(records)
+	case "yaml", "yml":
+		err := yaml.NewDecoder(r).Decode(records)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("unknown format %s, should be one of: csv, json or yaml", format)
+}
+
+// recordNames returns the name of each of the n records.
+func recordNames(n int, name func(i int) string) []string {
+	names := make([]string, n)
+
+	for i := range names {
+		names[i] = name(i)
+	}
+
+	return names
+}
+
+// joinList joins the members of a list into a single CSV field.
+func joinList(list []string) string {
+	return strings.Join(list, ";")
+}
+
+// splitList splits a CSV field into the members of a list, which can be separated by a semicolon or comma.
+func splitList(field string) []string {
+	var list []string
+
+	for _, m := range strings.FieldsFunc(field, func(r rune) bool { return r == ';' || r == ',' }) {
+		if m = strings.TrimSpace(m); m != "" {
+			list = append(list, m)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,218 @@
+package panos
+
+import (
+	"bytes"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTagsColors(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result><tag><entry name="prod"><color>color1</color></entry>` +
+			`<entry name="lab"><comments>no color</comments></entry></tag></result></response>`
+	})
+
+	tags, err := p.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Tag{{Name: "prod", Color: "Red"}, {Name: "lab", Comments: "no color"}}
+	if !reflect.DeepEqual(tags.Tags, want) {
+		t.Errorf("got %+v", tags.Tags)
+	}
+}
+
+func TestTagsExportImport(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+	tags := &Tags{Tags: []Tag{{Name: "prod", Color: "Red", Comments: "a, b"}, {Name: "lab"}}}
+
+	for _, format := range []string{"csv", "json", "yaml"} {
+		var buf bytes.Buffer
+
+		if err := tags.Export(&buf, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		start := len(d.sent("set"))
+
+		report, err := p.ImportTags(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		if len(report.Results) != 2 || len(report.Failed()) != 0 {
+			t.Errorf("%s: got %+v", format, report.Results)
+		}
+
+		sent := d.sent("set")[start:]
+		if len(sent) != 2 {
+			t.Fatalf("%s: sent %d requests, want 2", format, len(sent))
+		}
+
+		if element := sent[0].Get("element"); element != `<entry name="prod"><color>color1</color><comments>a, b</comments></entry>` {
+			t.Errorf("%s: got %s", format, element)
+		}
+
+		if element := sent[1].Get("element"); element != `<entry name="lab"></entry>` {
+			t.Errorf("%s: got %s", format, element)
+		}
+	}
+}
+
+func TestImportMergesExistingEntries(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") == "get" {
+			return `<response status="success"><result><service><entry name="web"><protocol><tcp><port>80</port>` +
+				`<override><yes><timeout>7200</timeout></yes></override></tcp></protocol></entry></service></result></response>`
+		}
+
+		return success
+	})
+
+	csv := "name,protocol,port,source_port,description,tags\nweb,tcp,8080,,,\nssh,tcp,22,,,\ndns,icmp,0,,,\n"
+
+	report, err := p.ImportServices(strings.NewReader(csv), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := []string{}
+	for _, res := range report.Results {
+		actions = append(actions, res.Action)
+	}
+
+	if !reflect.DeepEqual(actions, []string{"updated", "created", "created"}) || len(report.Failed()) != 1 || report.Failed()[0].Row != 3 {
+		t.Errorf("got %+v", report.Results)
+	}
+
+	if n := len(d.sent("edit")); n != 0 {
+		t.Errorf("sent %d edits, existing entries should be merged", n)
+	}
+
+	sent := d.sent("set")
+	if len(sent) != 2 || !strings.HasSuffix(sent[0].Get("xpath"), "/service") || strings.Contains(sent[0].Get("element"), "override") {
+		t.Errorf("unexpected requests %v", sent)
+	}
+}
+
+func TestAddressesExportImport(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+	addrs := &AddressObjects{Addresses: []Address{
+		{Name: "web", IPAddress: "10.1.1.1", Tags: []string{"prod", "dmz"}},
+		{Name: "site", FQDN: "example.com", Description: "a;b"},
+	}}
+
+	var buf bytes.Buffer
+	if err := addrs.Export(&buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "name,type,value,description,tags\nweb,ip,10.1.1.1,,prod;dmz\nsite,fqdn,example.com,a;b,\n"
+	if buf.String() != want {
+		t.Errorf("got %q", buf.String())
+	}
+
+	if _, err := p.ImportAddresses(&buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := d.sent("set")
+	if len(sent) != 2 || sent[0].Get("element") != `<entry name="web"><ip-netmask>10.1.1.1</ip-netmask><tag><member>prod</member><member>dmz</member></tag></entry>` {
+		t.Errorf("unexpected requests %v", sent)
+	}
+
+	if err := addrs.Export(&buf, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestAddressGroupsImport(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	csv := "name,type,members,filter,description\nservers,static,web;db,,\ntagged,dynamic,,'prod' and 'web',\nempty,static,,,\n"
+
+	report, err := p.ImportAddressGroups(strings.NewReader(csv), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Failed()) != 1 || report.Failed()[0].Name != "empty" {
+		t.Errorf("got %+v", report.Results)
+	}
+
+	want := []string{
+		`<entry name="servers"><static><member>web</member><member>db</member></static></entry>`,
+		`<entry name="tagged"><dynamic><filter>&#39;prod&#39; and &#39;web&#39;</filter></dynamic></entry>`,
+	}
+
+	sent := d.sent("set")
+	for i, element := range want {
+		if i >= len(sent) || sent[i].Get("element") != element {
+			t.Errorf("unexpected requests %v", sent)
+			break
+		}
+	}
+}
+
+func TestImportReplacesSettings(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") != "get" {
+			return success
+		}
+
+		if strings.HasSuffix(q.Get("xpath"), "/address") {
+			return `<response status="success"><result><address><entry name="web"><ip-netmask>10.1.1.1</ip-netmask>` +
+				`<description>old</description><tag><member>prod</member><member>dmz</member></tag>` +
+				`<disable-override>yes</disable-override></entry></address></result></response>`
+		}
+
+		return `<response status="success"><result><service><entry name="dns"><protocol><tcp><port>53</port>` +
+			`<source-port>1024</source-port></tcp></protocol></entry></service></result></response>`
+	})
+
+	csv := "name,type,value,description,tags\nweb,fqdn,web.example.com,,prod\n"
+	if report, err := p.ImportAddresses(strings.NewReader(csv), "csv"); err != nil || report.Results[0].Action != "updated" {
+		t.Fatalf("got %+v, %v", report, err)
+	}
+
+	entry := "/vsys/entry[@name='vsys1']/address/entry[@name='web']"
+	want := []string{
+		"delete " + entry + "/ip-netmask",
+		"delete " + entry + "/description",
+		"set /vsys/entry[@name='vsys1']/address",
+		"delete " + entry + "/tag/member[text()='dmz']",
+	}
+
+	var got []string
+	for _, q := range d.sent("") {
+		if q.Get("action") == "get" {
+			continue
+		}
+
+		xpath := q.Get("xpath")
+		got = append(got, q.Get("action")+" "+xpath[strings.Index(xpath, "/vsys/"):])
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q", got)
+	}
+
+	start := len(d.sent(""))
+
+	csv = "name,protocol,port,source_port,description,tags\ndns,udp,53,,,\n"
+	if _, err := p.ImportServices(strings.NewReader(csv), "csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := d.sent("")[start:]
+	if len(sent) != 3 || sent[1].Get("action") != "delete" || !strings.HasSuffix(sent[1].Get("xpath"), "[@name='dns']/protocol/tcp") {
+		t.Errorf("unexpected requests %v", sent)
+	}
+
+	if sent[2].Get("element") != `<entry name="dns"><protocol><udp><port>53</port></udp></protocol></entry>` {
+		t.Errorf("got %s", sent[2].Get("element"))
+	}
+}
@@ -0,0 +1,213 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Job contains information about a job on the device, such as a commit. Status is one of: ACT, PEND or FIN,
+// and Result is one of: OK, FAIL or PEND while the job is still running.
+type Job struct {
+	ID          string
+	Type        string
+	User        string
+	Description string
+	Status      string
+	Result      string
+	Progress    int
+	Queued      string
+	Enqueued    string
+	Finished    string
+	Details     []string
+	Warnings    []string
+	Devices     []JobDevice
+}
+
+// JobDevice contains the status of a job on each individual device, such as when pushing a commit to a
+// device-group from Panorama.
+type JobDevice struct {
+	Serial   string
+	Name     string
+	Vsys     string
+	Status   string
+	Result   string
+	Progress int
+	Errors   []string
+	Warnings []string
+}
+
+// commitResponse is used for parsing the job ID of a commit, validation or log query.
+type commitResponse struct {
+	XMLName xml.Name `xml:"response"`
+	Status  string   `xml:"status,attr"`
+	Code    string   `xml:"code,attr"`
+	Job     string   `xml:"result>job"`
+}
+
+// xmlJobs is used for parsing the status of a job.
+type xmlJobs struct {
+	XMLName xml.Name `xml:"response"`
+	Status  string   `xml:"status,attr"`
+	Code    string   `xml:"code,attr"`
+	Jobs    []xmlJob `xml:"result>job"`
+}
+
+// xmlJob is used for parsing each individual job.
+type xmlJob struct {
+	ID          string         `xml:"id"`
+	Type        string         `xml:"type"`
+	User        string         `xml:"user"`
+	Description string         `xml:"description"`
+	Status      string         `xml:"status"`
+	Result      string         `xml:"result"`
+	Progress    string         `xml:"progress"`
+	Queued      string         `xml:"queued"`
+	Enqueued    string         `xml:"tenq"`
+	Finished    string         `xml:"tfin"`
+	Details     []string       `xml:"details>line"`
+	Warnings    []string       `xml:"warnings>line"`
+	Devices     []xmlJobDevice `xml:"devices>entry"`
+}
+
+// xmlJobDevice is used for parsing the status of a job on each individual device.
+type xmlJobDevice struct {
+	Serial   string   `xml:"serial-no"`
+	Name     string   `xml:"devicename"`
+	Vsys     string   `xml:"vsysid"`
+	Status   string   `xml:"status"`
+	Result   string   `xml:"result"`
+	Progress string   `xml:"progress"`
+	Errors   []string `xml:"details>msg>errors>line"`
+	Warnings []string `xml:"details>msg>warnings>line"`
+}
+
+// jobPollInterval is how often WaitForJob checks on the status of a job.
+var jobPollInterval = 2 * time.Second
+
+// parseProgress converts the progress of a job into a percentage. Finished jobs sometimes report a timestamp
+// instead of a percentage, so they are always considered 100% complete.
+func parseProgress(status, progress string) int {
+	if status == "FIN" {
+		return 100
+	}
+
+	pct, err := strconv.Atoi(strings.TrimSpace(progress))
+	if err != nil {
+		return 0
+	}
+
+	return pct
+}
+
+// trimLines removes surrounding whitespace and empty lines from the given lines.
+func trimLines(lines []string) []string {
+	var trimmed []string
+
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			trimmed = append(trimmed, l)
+		}
+	}
+
+	return trimmed
+}
+
+// JobStatus returns the current status of the given job ID, as returned by Commit or CommitAll.
+func (p *PaloAlto) JobStatus(id string) (*Job, error) {
+	return p.JobStatusContext(context.Background(), id)
+}
+
+// JobStatusContext is the same as JobStatus, but uses the given context for all API requests.
+func (p *PaloAlto) JobStatusContext(ctx context.Context, id string) (*Job, error) {
+	var jobs xmlJobs
+
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid job ID %q, it must be a number", id)
+	}
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  fmt.Sprintf("<show><jobs><id>%s</id></jobs></show>", id),
+		"key":  p.Key,
+	}
+
+	jobData := p.send(ctx, "get", query)
+	if jobData.Error != nil {
+		return nil, jobData.Error
+	}
+
+	if err := xml.Unmarshal(jobData.Body, &jobs); err != nil {
+		return nil, err
+	}
+
+	if jobs.Status != "success" {
+		return nil, newAPIError(jobData, query)
+	}
+
+	if len(jobs.Jobs) <= 0 {
+		return nil, fmt.Errorf("job %s was not found", id)
+	}
+
+	j := jobs.Jobs[0]
+	job := &Job{
+		ID:          j.ID,
+		Type:        j.Type,
+		User:        j.User,
+		Description: j.Description,
+		Status:      j.Status,
+		Result:      j.Result,
+		Progress:    parseProgress(j.Status, j.Progress),
+		Queued:      j.Queued,
+		Enqueued:    j.Enqueued,
+		Finished:    j.Finished,
+		Details:     trimLines(j.Details),
+		Warnings:    trimLines(j.Warnings),
+	}
+
+	for _, d := range j.Devices {
+		job.Devices = append(job.Devices, JobDevice{
+			Serial:   d.Serial,
+			Name:     d.Name,
+			Vsys:     d.Vsys,
+			Status:   d.Status,
+			Result:   d.Result,
+			Progress: parseProgress(d.Status, d.Progress),
+			Errors:   trimLines(d.Errors),
+			Warnings: trimLines(d.Warnings),
+		})
+	}
+
+	return job, nil
+}
+
+// WaitForJob polls the given job ID until it has finished, or the context is done. If the job finishes
+// with a result of FAIL, then the job is returned along with an error, so you can inspect its details.
+func (p *PaloAlto) WaitForJob(ctx context.Context, id string) (*Job, error) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := p.JobStatusContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if job.Status == "FIN" {
+			if job.Result == "FAIL" {
+				return job, fmt.Errorf("job %s failed: %s", id, strings.Join(job.Details, " "))
+			}
+
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
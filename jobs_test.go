@@ -0,0 +1,86 @@
+package panos
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const finishedJob = `<response status="success"><result><job><id>42</id><type>CommitAll</type><user>admin</user>` +
+	`<status>FIN</status><result>OK</result><progress>16:04:05</progress><details><line>  Configuration committed  </line><line/></details>` +
+	`<devices><entry><serial-no>0123</serial-no><devicename>fw1</devicename><status>FIN</status><result>FAIL</result>` +
+	`<progress>100</progress><details><msg><errors><line>commit failed</line></errors></msg></details></entry></devices>` +
+	`</job></result></response>`
+
+func TestJobStatus(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return finishedJob
+	})
+
+	job, err := p.JobStatus("42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.ID != "42" || job.Progress != 100 || job.Result != "OK" || len(job.Details) != 1 || job.Details[0] != "Configuration committed" {
+		t.Errorf("got %+v", job)
+	}
+
+	if len(job.Devices) != 1 || job.Devices[0].Name != "fw1" || job.Devices[0].Errors[0] != "commit failed" {
+		t.Errorf("got devices %+v", job.Devices)
+	}
+
+	if cmd := d.sent("op")[0].Get("cmd"); cmd != "<show><jobs><id>42</id></jobs></show>" {
+		t.Errorf("unexpected command %s", cmd)
+	}
+}
+
+func TestJobStatusNotFound(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result/></response>`
+	})
+
+	if _, err := p.JobStatus("7"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	defer func(d time.Duration) { jobPollInterval = d }(jobPollInterval)
+	jobPollInterval = time.Millisecond
+
+	polls := 0
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		polls++
+		if polls < 3 {
+			return `<response status="success"><result><job><id>5</id><status>ACT</status><result>PEND</result><progress>50</progress></job></result></response>`
+		}
+
+		return `<response status="success"><result><job><id>5</id><status>FIN</status><result>FAIL</result><details><line>bad config</line></details></job></result></response>`
+	})
+
+	job, err := p.WaitForJob(context.Background(), "5")
+	if err == nil || !strings.Contains(err.Error(), "bad config") {
+		t.Errorf("err = %v", err)
+	}
+
+	if job == nil || job.Result != "FAIL" || polls != 3 {
+		t.Errorf("got job %+v after %d polls", job, polls)
+	}
+}
+
+func TestJobStatusInvalidID(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	for _, id := range []string{"", "4</id><foo>", "-1", "12a"} {
+		if _, err := p.JobStatus(id); err == nil {
+			t.Errorf("expected an error for job ID %q", id)
+		}
+	}
+
+	if len(d.sent("")) != 0 {
+		t.Error("invalid job IDs should not be sent")
+	}
+}
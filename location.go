@@ -0,0 +1,152 @@
+package panos
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Location specifies where in the configuration an object or rule lives. Use one of SharedLocation, VsysLocation,
+// DeviceGroupLocation, TemplateLocation or TemplateStackLocation to create one. Vsys is used on its own to target a
+// vsys on a firewall, or along with Template or TemplateStack to target a vsys within a template on Panorama.
+//
+// Locations are passed as the last parameter to each call. When no location is given, the session's vsys (see
+// WithVsys) or vsys1 is used on a firewall, while Panorama requires a location for everything but listing objects.
+type Location struct {
+	Shared        bool
+	Vsys          string
+	DeviceGroup   string
+	Template      string
+	TemplateStack string
+}
+
+// SharedLocation returns the location of shared objects, which are available to every vsys on a firewall, or every
+// device-group on Panorama.
+func SharedLocation() Location {
+	return Location{Shared: true}
+}
+
+// VsysLocation returns the location of the given vsys on a firewall, i.e. "vsys2".
+func VsysLocation(vsys string) Location {
+	return Location{Vsys: vsys}
+}
+
+// DeviceGroupLocation returns the location of the given device-group on Panorama.
+func DeviceGroupLocation(devicegroup string) Location {
+	return Location{DeviceGroup: devicegroup}
+}
+
+// TemplateLocation returns the location of the given vsys within a template on Panorama. If vsys is empty, then
+// vsys1 is used.
+func TemplateLocation(template, vsys string) Location {
+	return Location{Template: template, Vsys: vsys}
+}
+
+// TemplateStackLocation returns the location of the given vsys within a template stack on Panorama. If vsys is empty,
+// then vsys1 is used.
+func TemplateStackLocation(stack, vsys string) Location {
+	return Location{TemplateStack: stack, Vsys: vsys}
+}
+
+// Xpath returns the xpath of the location, which the type of object is added to, i.e. Xpath() + "/address".
+func (l Location) Xpath() (string, error) {
+	scopes := 0
+	vsys := l.Vsys
+
+	for _, set := range []bool{l.Shared, l.DeviceGroup != "", l.Template != "", l.TemplateStack != ""} {
+		if set {
+			scopes++
+		}
+	}
+
+	if scopes > 1 {
+		return "", errors.New("a location can only be one of: shared, device-group, template or template-stack")
+	}
+
+	if vsys == "" {
+		vsys = "vsys1"
+	}
+
+	switch {
+	case l.Shared && l.Vsys != "", l.DeviceGroup != "" && l.Vsys != "":
+		return "", errors.New("a vsys can only be specified on its own, or along with a template or template-stack")
+	case l.Shared:
+		return "/config/shared", nil
+	case l.DeviceGroup != "":
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]", xpathLiteral(l.DeviceGroup)), nil
+	case l.Template != "":
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name=%s]", xpathLiteral(l.Template), xpathLiteral(vsys)), nil
+	case l.TemplateStack != "":
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name=%s]", xpathLiteral(l.TemplateStack), xpathLiteral(vsys)), nil
+	case l.Vsys != "":
+		return fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name=%s]", xpathLiteral(l.Vsys)), nil
+	}
+
+	return "", errors.New("the location is empty, you must specify where the object is")
+}
+
+// String returns a short description of the location, i.e. "device-group Branch-Offices".
+func (l Location) String() string {
+	switch {
+	case l.Shared:
+		return "shared"
+	case l.DeviceGroup != "":
+		return "device-group " + l.DeviceGroup
+	case l.Template != "":
+		return fmt.Sprintf("template %s (%s)", l.Template, l.vsysName())
+	case l.TemplateStack != "":
+		return fmt.Sprintf("template-stack %s (%s)", l.TemplateStack, l.vsysName())
+	}
+
+	return l.vsysName()
+}
+
+// vsysName returns the name of the vsys, or vsys1 if one was not specified.
+func (l Location) vsysName() string {
+	if l.Vsys == "" {
+		return "vsys1"
+	}
+
+	return l.Vsys
+}
+
+// location returns the location to use for a call, given the optional location parameter. On a firewall, the
+// session's vsys (or vsys1) is used when no location is given, and Panorama requires a location.
+func (p *PaloAlto) location(loc []Location) (Location, error) {
+	var l Location
+
+	if len(loc) > 0 {
+		l = loc[0]
+	}
+
+	if p.DeviceType != "panorama" {
+		if l.DeviceGroup != "" || l.Template != "" || l.TemplateStack != "" {
+			return l, errors.New("you must be connected to a Panorama device when specifying a device-group, template or template-stack")
+		}
+
+		if !l.Shared && l.Vsys == "" {
+			l.Vsys = p.vsys
+		}
+
+		if !l.Shared && l.Vsys == "" {
+			l.Vsys = "vsys1"
+		}
+
+		return l, nil
+	}
+
+	if !l.Shared && l.DeviceGroup == "" && l.Template == "" && l.TemplateStack == "" {
+		return l, errors.New("you must specify a location (i.e. a device-group) when connected to a Panorama device")
+	}
+
+	return l, nil
+}
+
+// locationXpath returns the xpath of the location to use for a call, given the optional location parameter.
+func (p *PaloAlto) locationXpath(loc []Location) (string, error) {
+	l, err := p.location(loc)
+	if err != nil {
+		return "", err
+	}
+
+	return l.Xpath()
+}
@@ -0,0 +1,57 @@
+package panos
+
+import "testing"
+
+func TestLocationXpath(t *testing.T) {
+	tests := []struct {
+		loc  Location
+		want string
+		ok   bool
+	}{
+		{SharedLocation(), "/config/shared", true},
+		{VsysLocation("vsys2"), "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys2']", true},
+		{DeviceGroupLocation("bob's"), `/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name="bob's"]`, true},
+		{
+			TemplateLocation("branch", ""),
+			"/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='branch']/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']",
+			true,
+		},
+		{Location{}, "", false},
+		{Location{Shared: true, DeviceGroup: "lab"}, "", false},
+		{Location{DeviceGroup: "lab", Vsys: "vsys2"}, "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.loc.Xpath()
+		if (err == nil) != tt.ok {
+			t.Errorf("%+v: err = %v", tt.loc, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.loc, got, tt.want)
+		}
+	}
+}
+
+func TestSessionLocation(t *testing.T) {
+	p, _ := newFakeDevice(t, nil)
+
+	if l, err := p.location(nil); err != nil || l.Vsys != "vsys1" {
+		t.Errorf("got %+v, %v", l, err)
+	}
+
+	p.vsys = "vsys3"
+	if l, err := p.location(nil); err != nil || l.Vsys != "vsys3" {
+		t.Errorf("got %+v, %v", l, err)
+	}
+
+	if _, err := p.location([]Location{DeviceGroupLocation("lab")}); err == nil {
+		t.Error("expected an error for a device-group on a firewall")
+	}
+
+	p.DeviceType = "panorama"
+	if _, err := p.location(nil); err == nil {
+		t.Error("expected an error for an empty location on Panorama")
+	}
+}
@@ -0,0 +1,338 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// LogQuery contains the settings for retrieving logs. Type should be one of: traffic, threat, url, system or config.
+// Query is a filter expression written the same way as in the web interface, i.e. "( addr.src in 10.1.1.1 )". NLogs
+// is the number of logs to return (1-5000, the default is 20), and Skip is the number of logs to skip over. Direction
+// is one of: backward (newest first, the default) or forward.
+type LogQuery struct {
+	Type      string
+	Query     string
+	NLogs     int
+	Skip      int
+	Direction string
+}
+
+// Logs contains a slice of all log entries returned by a query.
+type Logs struct {
+	Logs []LogEntry
+}
+
+// LogEntry contains information about each individual log entry. Only the fields that apply to the type of log
+// being queried are filled in, and every field returned by the device can be found in Fields.
+type LogEntry struct {
+	// Common to all log types.
+	LogID         string
+	Serial        string
+	DeviceName    string
+	Vsys          string
+	Type          string
+	Subtype       string
+	ReceiveTime   string
+	TimeGenerated string
+	Severity      string
+	Description   string
+
+	// Traffic, threat and URL logs.
+	Source           string
+	Destination      string
+	SourcePort       string
+	DestinationPort  string
+	NATSource        string
+	NATDestination   string
+	Protocol         string
+	Application      string
+	Rule             string
+	Action           string
+	FromZone         string
+	ToZone           string
+	SourceUser       string
+	SessionID        string
+	Bytes            string
+	Packets          string
+	SessionEndReason string
+
+	// Threat and URL logs.
+	ThreatID  string
+	Category  string
+	Direction string
+	Misc      string
+
+	// System logs.
+	EventID string
+	Module  string
+
+	// Config logs.
+	Admin   string
+	Command string
+	Path    string
+	Result  string
+	Before  string
+	After   string
+
+	// Fields contains every field of the log entry, keyed by the name used by the device, i.e. "src".
+	Fields map[string]string
+}
+
+// LogIterator pages through the results of a log query. Call Next to advance to each log entry, and Err once
+// Next returns false to see if the iteration stopped because of an error.
+type LogIterator struct {
+	p       *PaloAlto
+	ctx     context.Context
+	query   LogQuery
+	entries []LogEntry
+	current LogEntry
+	done    bool
+	err     error
+}
+
+// xmlLogs is used for parsing the results of a log query.
+type xmlLogs struct {
+	XMLName   xml.Name      `xml:"response"`
+	Status    string        `xml:"status,attr"`
+	Code      string        `xml:"code,attr"`
+	JobStatus string        `xml:"result>job>status"`
+	Entries   []xmlLogEntry `xml:"result>log>logs>entry"`
+}
+
+// xmlLogEntry is used for parsing each individual log entry, whose fields vary by log type.
+type xmlLogEntry struct {
+	Attrs  []xml.Attr    `xml:",any,attr"`
+	Fields []xmlLogField `xml:",any"`
+}
+
+// xmlLogField is used for parsing each individual field of a log entry.
+type xmlLogField struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// entry converts the parsed log entry into a LogEntry.
+func (x *xmlLogEntry) entry() LogEntry {
+	f := map[string]string{}
+
+	for _, a := range x.Attrs {
+		f[a.Name.Local] = a.Value
+	}
+
+	for _, field := range x.Fields {
+		f[field.XMLName.Local] = field.Value
+	}
+
+	return LogEntry{
+		LogID:            f["logid"],
+		Serial:           f["serial"],
+		DeviceName:       f["device_name"],
+		Vsys:             f["vsys"],
+		Type:             f["type"],
+		Subtype:          f["subtype"],
+		ReceiveTime:      f["receive_time"],
+		TimeGenerated:    f["time_generated"],
+		Severity:         f["severity"],
+		Description:      f["opaque"],
+		Source:           f["src"],
+		Destination:      f["dst"],
+		SourcePort:       f["sport"],
+		DestinationPort:  f["dport"],
+		NATSource:        f["natsrc"],
+		NATDestination:   f["natdst"],
+		Protocol:         f["proto"],
+		Application:      f["app"],
+		Rule:             f["rule"],
+		Action:           f["action"],
+		FromZone:         f["from"],
+		ToZone:           f["to"],
+		SourceUser:       f["srcuser"],
+		SessionID:        f["sessionid"],
+		Bytes:            f["bytes"],
+		Packets:          f["packets"],
+		SessionEndReason: f["session_end_reason"],
+		ThreatID:         f["threatid"],
+		Category:         f["category"],
+		Direction:        f["direction"],
+		Misc:             f["misc"],
+		EventID:          f["eventid"],
+		Module:           f["module"],
+		Admin:            f["admin"],
+		Command:          f["cmd"],
+		Path:             f["path"],
+		Result:           f["result"],
+		Before:           f["before-change-detail"],
+		After:            f["after-change-detail"],
+		Fields:           f,
+	}
+}
+
+// Logs submits a log query, waits for it to finish, and returns the matching log entries. To retrieve more than
+// 5000 logs, use LogIterator instead.
+func (p *PaloAlto) Logs(query LogQuery) (*Logs, error) {
+	return p.LogsContext(context.Background(), query)
+}
+
+// LogsContext is the same as Logs, but uses the given context for all API requests.
+func (p *PaloAlto) LogsContext(ctx context.Context, query LogQuery) (*Logs, error) {
+	var job commitResponse
+	var logs Logs
+
+	if query.Type == "" {
+		return nil, errors.New("you must specify the type of logs to retrieve")
+	}
+
+	q := map[string]string{
+		"type":     "log",
+		"log-type": query.Type,
+		"key":      p.Key,
+	}
+
+	if query.Query != "" {
+		q["query"] = query.Query
+	}
+
+	if query.NLogs > 0 {
+		q["nlogs"] = strconv.Itoa(query.NLogs)
+	}
+
+	if query.Skip > 0 {
+		q["skip"] = strconv.Itoa(query.Skip)
+	}
+
+	if query.Direction != "" {
+		q["dir"] = query.Direction
+	}
+
+	resp := p.send(ctx, "get", q)
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &job); err != nil {
+		return nil, err
+	}
+
+	if job.Status != "success" {
+		return nil, newAPIError(resp, q)
+	}
+
+	entries, err := p.logResults(ctx, job.Job)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		logs.Logs = append(logs.Logs, e.entry())
+	}
+
+	return &logs, nil
+}
+
+// logResults polls the given log query job until it has finished, and returns the log entries.
+func (p *PaloAlto) logResults(ctx context.Context, id string) ([]xmlLogEntry, error) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	query := map[string]string{
+		"type":   "log",
+		"action": "get",
+		"job-id": id,
+		"key":    p.Key,
+	}
+
+	for {
+		var logs xmlLogs
+
+		logData := p.send(ctx, "get", query)
+		if logData.Error != nil {
+			return nil, logData.Error
+		}
+
+		if err := xml.Unmarshal(logData.Body, &logs); err != nil {
+			return nil, err
+		}
+
+		if logs.Status != "success" {
+			return nil, newAPIError(logData, query)
+		}
+
+		if logs.JobStatus == "FIN" {
+			return logs.Entries, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// LogIterator returns an iterator that pages through all of the logs matching the query, retrieving NLogs
+// entries at a time (default 100), starting at Skip.
+//
+//	it := pa.LogIterator(ctx, panos.LogQuery{Type: "traffic", Query: "( app eq dns )"})
+//	for it.Next() {
+//		fmt.Println(it.Entry().Source)
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func (p *PaloAlto) LogIterator(ctx context.Context, query LogQuery) *LogIterator {
+	if query.NLogs <= 0 {
+		query.NLogs = 100
+	}
+
+	return &LogIterator{
+		p:     p,
+		ctx:   ctx,
+		query: query,
+	}
+}
+
+// Next advances the iterator to the next log entry, retrieving the next page of logs when needed. It returns
+// false when there are no more entries, or an error occurred.
+func (it *LogIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.entries) <= 0 && !it.done {
+		logs, err := it.p.LogsContext(it.ctx, it.query)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.entries = logs.Logs
+		it.query.Skip += len(logs.Logs)
+
+		if len(logs.Logs) < it.query.NLogs {
+			it.done = true
+		}
+	}
+
+	if len(it.entries) <= 0 {
+		return false
+	}
+
+	it.current = it.entries[0]
+	it.entries = it.entries[1:]
+
+	return true
+}
+
+// Entry returns the current log entry.
+func (it *LogIterator) Entry() LogEntry {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *LogIterator) Err() error {
+	return it.err
+}
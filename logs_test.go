@@ -0,0 +1,96 @@
+package panos
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLogs(t *testing.T) {
+	defer func(d time.Duration) { jobPollInterval = d }(jobPollInterval)
+	jobPollInterval = time.Millisecond
+
+	polls := 0
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") != "get" {
+			return `<response status="success"><result><job>7</job></result></response>`
+		}
+
+		if polls++; polls == 1 {
+			return `<response status="success"><result><job><status>ACT</status></job></result></response>`
+		}
+
+		return `<response status="success"><result><job><status>FIN</status></job><log><logs count="1">` +
+			`<entry logid="7001"><src>10.1.1.1</src><dst>10.2.2.2</dst><dport>443</dport><app>ssl</app>` +
+			`<from>trust</from><to>untrust</to><session_end_reason>aged-out</session_end_reason></entry>` +
+			`</logs></log></result></response>`
+	})
+
+	logs, err := p.Logs(LogQuery{Type: "traffic", Query: "( port.dst eq 443 )", NLogs: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs.Logs) != 1 {
+		t.Fatalf("got %+v", logs.Logs)
+	}
+
+	e := logs.Logs[0]
+	if e.LogID != "7001" || e.Source != "10.1.1.1" || e.DestinationPort != "443" || e.Application != "ssl" ||
+		e.FromZone != "trust" || e.ToZone != "untrust" || e.SessionEndReason != "aged-out" || e.Fields["dst"] != "10.2.2.2" {
+		t.Errorf("got %+v", e)
+	}
+
+	q := d.sent("log")[0]
+	if q.Get("log-type") != "traffic" || q.Get("query") != "( port.dst eq 443 )" || q.Get("nlogs") != "10" || q.Get("skip") != "" {
+		t.Errorf("unexpected query %v", q)
+	}
+
+	if polls != 2 || d.sent("get")[1].Get("job-id") != "7" {
+		t.Errorf("polled %d times", polls)
+	}
+
+	if _, err := p.Logs(LogQuery{}); err == nil {
+		t.Error("expected an error for a missing log type")
+	}
+}
+
+func TestLogIterator(t *testing.T) {
+	var skip int
+	var skips []string
+
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") != "get" {
+			skips = append(skips, q.Get("skip"))
+			skip, _ = strconv.Atoi(q.Get("skip"))
+
+			return `<response status="success"><result><job>1</job></result></response>`
+		}
+
+		// Five logs in total, returned two at a time.
+		entries := ""
+		for i := skip; i < skip+2 && i < 5; i++ {
+			entries += fmt.Sprintf(`<entry><eventid>%d</eventid></entry>`, i)
+		}
+
+		return `<response status="success"><result><job><status>FIN</status></job><log><logs>` + entries + `</logs></log></result></response>`
+	})
+
+	var got []string
+	it := p.LogIterator(context.Background(), LogQuery{Type: "system", NLogs: 2})
+
+	for it.Next() {
+		got = append(got, it.Entry().EventID)
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(got) != "[0 1 2 3 4]" || fmt.Sprint(skips) != "[ 2 4]" {
+		t.Errorf("got %v, skips %q", got, skips)
+	}
+}
@@ -0,0 +1,369 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+)
+
+// NATRules contains a slice of all NAT rules.
+type NATRules struct {
+	Rules []NATRule
+}
+
+// NATRule contains information about each individual NAT rule. SourceTranslationType should be one of:
+// dynamic-ip-and-port, dynamic-ip, static-ip, or an empty string for no source translation. When using
+// dynamic-ip-and-port, you can translate to an interface's address by specifying SourceTranslationInterface
+// instead of SourceTranslatedAddresses. A static-ip translation uses the first translated address.
+type NATRule struct {
+	Name                         string
+	Description                  string
+	Tags                         []string
+	NATType                      string
+	From                         []string
+	To                           []string
+	ToInterface                  string
+	Source                       []string
+	Destination                  []string
+	Service                      string
+	Disabled                     string
+	SourceTranslationType        string
+	SourceTranslatedAddresses    []string
+	SourceTranslationInterface   string
+	SourceTranslationInterfaceIP string
+	StaticBiDirectional          string
+	DestinationTranslatedAddress string
+	DestinationTranslatedPort    string
+}
+
+// xmlNATRules is used for parsing all NAT rules.
+type xmlNATRules struct {
+	XMLName xml.Name     `xml:"response"`
+	Status  string       `xml:"status,attr"`
+	Code    string       `xml:"code,attr"`
+	Rules   []xmlNATRule `xml:"result>rules>entry"`
+}
+
+// xmlNATRule is used for parsing and creating each individual NAT rule.
+type xmlNATRule struct {
+	XMLName                xml.Name                   `xml:"entry"`
+	Name                   string                     `xml:"name,attr"`
+	Description            string                     `xml:"description,omitempty"`
+	Tags                   *xmlMembers                `xml:"tag,omitempty"`
+	NATType                string                     `xml:"nat-type,omitempty"`
+	From                   *xmlMembers                `xml:"from,omitempty"`
+	To                     *xmlMembers                `xml:"to,omitempty"`
+	ToInterface            string                     `xml:"to-interface,omitempty"`
+	Source                 *xmlMembers                `xml:"source,omitempty"`
+	Destination            *xmlMembers                `xml:"destination,omitempty"`
+	Service                string                     `xml:"service,omitempty"`
+	Disabled               string                     `xml:"disabled,omitempty"`
+	SourceTranslation      *xmlSourceTranslation      `xml:"source-translation,omitempty"`
+	DestinationTranslation *xmlDestinationTranslation `xml:"destination-translation,omitempty"`
+	Other                  []xmlElement               `xml:",any"`
+}
+
+// xmlSourceTranslation is used for parsing and creating the source translation of a NAT rule.
+type xmlSourceTranslation struct {
+	DynamicIPAndPort *xmlDynamicIPAndPort `xml:"dynamic-ip-and-port,omitempty"`
+	DynamicIP        *xmlDynamicIP        `xml:"dynamic-ip,omitempty"`
+	StaticIP         *xmlStaticIP         `xml:"static-ip,omitempty"`
+}
+
+// xmlDynamicIPAndPort is used for parsing and creating a dynamic-ip-and-port source translation.
+type xmlDynamicIPAndPort struct {
+	TranslatedAddress *xmlMembers          `xml:"translated-address,omitempty"`
+	InterfaceAddress  *xmlInterfaceAddress `xml:"interface-address,omitempty"`
+}
+
+// xmlInterfaceAddress is used for parsing and creating a translation to an interface's address.
+type xmlInterfaceAddress struct {
+	Interface string `xml:"interface"`
+	IP        string `xml:"ip,omitempty"`
+}
+
+// xmlDynamicIP is used for parsing and creating a dynamic-ip source translation.
+type xmlDynamicIP struct {
+	TranslatedAddress *xmlMembers  `xml:"translated-address,omitempty"`
+	Other             []xmlElement `xml:",any"`
+}
+
+// xmlStaticIP is used for parsing and creating a static-ip source translation.
+type xmlStaticIP struct {
+	TranslatedAddress string `xml:"translated-address"`
+	BiDirectional     string `xml:"bi-directional,omitempty"`
+}
+
+// xmlDestinationTranslation is used for parsing and creating the destination translation of a NAT rule.
+type xmlDestinationTranslation struct {
+	TranslatedAddress string       `xml:"translated-address,omitempty"`
+	TranslatedPort    string       `xml:"translated-port,omitempty"`
+	Other             []xmlElement `xml:",any"`
+}
+
+// toXML converts the rule into the format used when sending it to the device.
+func (r *NATRule) toXML() (*xmlNATRule, error) {
+	x := &xmlNATRule{
+		Name:        r.Name,
+		Description: r.Description,
+		Tags:        newMembers(r.Tags...),
+		NATType:     r.NATType,
+		From:        newMembers(r.From...),
+		To:          newMembers(r.To...),
+		ToInterface: r.ToInterface,
+		Source:      newMembers(r.Source...),
+		Destination: newMembers(r.Destination...),
+		Service:     r.Service,
+		Disabled:    r.Disabled,
+	}
+
+	switch r.SourceTranslationType {
+	case "":
+	case "dynamic-ip-and-port":
+		dipp := &xmlDynamicIPAndPort{}
+
+		if r.SourceTranslationInterface != "" {
+			dipp.InterfaceAddress = &xmlInterfaceAddress{Interface: r.SourceTranslationInterface, IP: r.SourceTranslationInterfaceIP}
+		} else {
+			dipp.TranslatedAddress = newMembers(r.SourceTranslatedAddresses...)
+		}
+
+		if dipp.InterfaceAddress == nil && dipp.TranslatedAddress == nil {
+			return nil, errors.New("you must specify a translated address or interface for a dynamic-ip-and-port translation")
+		}
+
+		x.SourceTranslation = &xmlSourceTranslation{DynamicIPAndPort: dipp}
+	case "dynamic-ip":
+		if len(r.SourceTranslatedAddresses) <= 0 {
+			return nil, errors.New("you must specify a translated address for a dynamic-ip translation")
+		}
+
+		x.SourceTranslation = &xmlSourceTranslation{DynamicIP: &xmlDynamicIP{TranslatedAddress: newMembers(r.SourceTranslatedAddresses...)}}
+	case "static-ip":
+		if len(r.SourceTranslatedAddresses) <= 0 {
+			return nil, errors.New("you must specify a translated address for a static-ip translation")
+		}
+
+		x.SourceTranslation = &xmlSourceTranslation{StaticIP: &xmlStaticIP{TranslatedAddress: r.SourceTranslatedAddresses[0], BiDirectional: r.StaticBiDirectional}}
+	default:
+		return nil, errors.New("source translation type must be one of: dynamic-ip-and-port, dynamic-ip or static-ip")
+	}
+
+	if r.DestinationTranslatedAddress != "" {
+		x.DestinationTranslation = &xmlDestinationTranslation{TranslatedAddress: r.DestinationTranslatedAddress, TranslatedPort: r.DestinationTranslatedPort}
+	}
+
+	return x, nil
+}
+
+// rule converts the parsed rule into a NATRule.
+func (x *xmlNATRule) rule() NATRule {
+	r := NATRule{
+		Name:        x.Name,
+		Description: x.Description,
+		Tags:        x.Tags.list(),
+		NATType:     x.NATType,
+		From:        x.From.list(),
+		To:          x.To.list(),
+		ToInterface: x.ToInterface,
+		Source:      x.Source.list(),
+		Destination: x.Destination.list(),
+		Service:     x.Service,
+		Disabled:    x.Disabled,
+	}
+
+	if st := x.SourceTranslation; st != nil {
+		switch {
+		case st.DynamicIPAndPort != nil:
+			r.SourceTranslationType = "dynamic-ip-and-port"
+			r.SourceTranslatedAddresses = st.DynamicIPAndPort.TranslatedAddress.list()
+
+			if ia := st.DynamicIPAndPort.InterfaceAddress; ia != nil {
+				r.SourceTranslationInterface = ia.Interface
+				r.SourceTranslationInterfaceIP = ia.IP
+			}
+		case st.DynamicIP != nil:
+			r.SourceTranslationType = "dynamic-ip"
+			r.SourceTranslatedAddresses = st.DynamicIP.TranslatedAddress.list()
+		case st.StaticIP != nil:
+			r.SourceTranslationType = "static-ip"
+			r.SourceTranslatedAddresses = []string{st.StaticIP.TranslatedAddress}
+			r.StaticBiDirectional = st.StaticIP.BiDirectional
+		}
+	}
+
+	if dt := x.DestinationTranslation; dt != nil {
+		r.DestinationTranslatedAddress = dt.TranslatedAddress
+		r.DestinationTranslatedPort = dt.TranslatedPort
+	}
+
+	return r
+}
+
+// NATRules returns information about all of the NAT rules. When ran against a Panorama device, rulebase must be one
+// of: pre or post, and you must specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a
+// firewall, the rulebase is ignored, and you can (optionally) specify a VsysLocation as the last parameter.
+func (p *PaloAlto) NATRules(rulebase string, loc ...Location) (*NATRules, error) {
+	return p.NATRulesContext(context.Background(), rulebase, loc...)
+}
+
+// NATRulesContext is the same as NATRules, but uses the given context for all API requests.
+func (p *PaloAlto) NATRulesContext(ctx context.Context, rulebase string, loc ...Location) (*NATRules, error) {
+	var parsedRules xmlNATRules
+	var rules NATRules
+
+	xpath, err := p.rulebaseXpath("nat", rulebase, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	query := map[string]string{
+		"type":   "config",
+		"action": "get",
+		"xpath":  xpath,
+		"key":    p.Key,
+	}
+
+	ruleData := p.send(ctx, "get", query)
+	if ruleData.Error != nil {
+		return nil, ruleData.Error
+	}
+
+	if err := xml.Unmarshal(ruleData.Body, &parsedRules); err != nil {
+		return nil, err
+	}
+
+	if parsedRules.Status != "success" {
+		return nil, newAPIError(ruleData, query)
+	}
+
+	for _, r := range parsedRules.Rules {
+		rules.Rules = append(rules.Rules, r.rule())
+	}
+
+	return &rules, nil
+}
+
+// CreateNATRule adds a new NAT rule to the bottom of the rulebase. Any zones, addresses or service that are not
+// specified default to "any." When creating a rule on a Panorama device, rulebase must be one of: pre or post, and
+// you must specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you
+// can specify a VsysLocation instead.
+func (p *PaloAlto) CreateNATRule(rule *NATRule, rulebase string, loc ...Location) error {
+	return p.CreateNATRuleContext(context.Background(), rule, rulebase, loc...)
+}
+
+// CreateNATRuleContext is the same as CreateNATRule, but uses the given context for all API requests.
+func (p *PaloAlto) CreateNATRuleContext(ctx context.Context, rule *NATRule, rulebase string, loc ...Location) error {
+	if rule == nil || rule.Name == "" {
+		return errors.New("you must specify a name for the NAT rule")
+	}
+
+	if err := validateName(rule.Name); err != nil {
+		return err
+	}
+
+	xpath, err := p.rulebaseXpath("nat", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	r := *rule
+	for _, members := range []*[]string{&r.From, &r.To, &r.Source, &r.Destination} {
+		if len(*members) <= 0 {
+			*members = []string{"any"}
+		}
+	}
+
+	if r.Service == "" {
+		r.Service = "any"
+	}
+
+	x, err := r.toXML()
+	if err != nil {
+		return err
+	}
+
+	return p.setEntry(ctx, "set", xpath, r.Name, x)
+}
+
+// EditNATRule replaces the existing NAT rule of the same name with the given rule. Every field of the rule is
+// overwritten, so you should start from a rule returned by NATRules. Any settings that NATRule doesn't have a field
+// for, such as target devices or the active/active device binding, are read from the existing rule and kept as they
+// are. When editing a rule on a Panorama device, rulebase must be one of: pre or post, and you must specify its
+// location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can specify a
+// VsysLocation instead.
+func (p *PaloAlto) EditNATRule(rule *NATRule, rulebase string, loc ...Location) error {
+	return p.EditNATRuleContext(context.Background(), rule, rulebase, loc...)
+}
+
+// EditNATRuleContext is the same as EditNATRule, but uses the given context for all API requests.
+func (p *PaloAlto) EditNATRuleContext(ctx context.Context, rule *NATRule, rulebase string, loc ...Location) error {
+	if rule == nil || rule.Name == "" {
+		return errors.New("you must specify a name for the NAT rule")
+	}
+
+	var existing xmlNATRule
+
+	xpath, err := p.rulebaseXpath("nat", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	x, err := rule.toXML()
+	if err != nil {
+		return err
+	}
+
+	if err := p.getEntry(ctx, xpath, rule.Name, &existing); err != nil {
+		return err
+	}
+
+	x.Other = existing.Other
+
+	// The fallback of a dynamic-ip translation, and the DNS rewrite of a destination translation, are kept as long as
+	// the rule still uses the same kind of translation.
+	if st, old := x.SourceTranslation, existing.SourceTranslation; st != nil && old != nil && st.DynamicIP != nil && old.DynamicIP != nil {
+		st.DynamicIP.Other = old.DynamicIP.Other
+	}
+
+	if dt, old := x.DestinationTranslation, existing.DestinationTranslation; dt != nil && old != nil {
+		dt.Other = old.Other
+	}
+
+	return p.setEntry(ctx, "edit", xpath, rule.Name, x)
+}
+
+// DeleteNATRule removes the given NAT rule. When deleting a rule on a Panorama device, rulebase must be one of: pre
+// or post, and you must specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys
+// firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) DeleteNATRule(name, rulebase string, loc ...Location) error {
+	return p.DeleteNATRuleContext(context.Background(), name, rulebase, loc...)
+}
+
+// DeleteNATRuleContext is the same as DeleteNATRule, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteNATRuleContext(ctx context.Context, name, rulebase string, loc ...Location) error {
+	xpath, err := p.rulebaseXpath("nat", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	return p.deleteRule(ctx, xpath, name)
+}
+
+// MoveNATRule moves the given NAT rule within the rulebase. Where must be one of: top, bottom, before or after. When
+// moving a rule before or after another, specify that rule's name as dest, otherwise use an empty string. When
+// moving a rule on a Panorama device, rulebase must be one of: pre or post, and you must specify its location (i.e.
+// a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) MoveNATRule(name, where, dest, rulebase string, loc ...Location) error {
+	return p.MoveNATRuleContext(context.Background(), name, where, dest, rulebase, loc...)
+}
+
+// MoveNATRuleContext is the same as MoveNATRule, but uses the given context for all API requests.
+func (p *PaloAlto) MoveNATRuleContext(ctx context.Context, name, where, dest, rulebase string, loc ...Location) error {
+	xpath, err := p.rulebaseXpath("nat", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	return p.moveRule(ctx, xpath, name, where, dest)
+}
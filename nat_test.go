@@ -0,0 +1,115 @@
+package panos
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// existingNATRule is a rule as returned by the device, including settings NATRule has no fields for.
+const existingNATRule = `<entry name="outbound"><from><member>trust</member></from><to><member>untrust</member></to>` +
+	`<source><member>any</member></source><destination><member>any</member></destination><service>any</service>` +
+	`<source-translation><dynamic-ip><translated-address><member>pool</member></translated-address>` +
+	`<fallback><interface-address><interface>ethernet1/1</interface></interface-address></fallback></dynamic-ip></source-translation>` +
+	`<target><negate>no</negate></target><active-active-device-binding>primary</active-active-device-binding></entry>`
+
+func TestNATRuleToXML(t *testing.T) {
+	tests := []struct {
+		name string
+		rule NATRule
+		want string
+		ok   bool
+	}{
+		{
+			name: "interface",
+			rule: NATRule{Name: "a", SourceTranslationType: "dynamic-ip-and-port", SourceTranslationInterface: "ethernet1/1"},
+			want: `<entry name="a"><source-translation><dynamic-ip-and-port><interface-address><interface>ethernet1/1</interface></interface-address></dynamic-ip-and-port></source-translation></entry>`,
+			ok:   true,
+		},
+		{
+			name: "static",
+			rule: NATRule{Name: "b", SourceTranslationType: "static-ip", SourceTranslatedAddresses: []string{"1.1.1.1"}, StaticBiDirectional: "yes"},
+			want: `<entry name="b"><source-translation><static-ip><translated-address>1.1.1.1</translated-address><bi-directional>yes</bi-directional></static-ip></source-translation></entry>`,
+			ok:   true,
+		},
+		{
+			name: "destination",
+			rule: NATRule{Name: "c", DestinationTranslatedAddress: "10.1.1.1", DestinationTranslatedPort: "8080"},
+			want: `<entry name="c"><destination-translation><translated-address>10.1.1.1</translated-address><translated-port>8080</translated-port></destination-translation></entry>`,
+			ok:   true,
+		},
+		{name: "no address", rule: NATRule{Name: "d", SourceTranslationType: "dynamic-ip"}},
+		{name: "unknown type", rule: NATRule{Name: "e", SourceTranslationType: "bogus"}},
+	}
+
+	for _, tt := range tests {
+		x, err := tt.rule.toXML()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.name, err)
+			continue
+		}
+
+		if !tt.ok {
+			continue
+		}
+
+		b, _ := xml.Marshal(x)
+		if string(b) != tt.want {
+			t.Errorf("%s: got %s", tt.name, b)
+		}
+	}
+}
+
+func TestNATRulesParse(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result><rules>` + existingNATRule + `</rules></result></response>`
+	})
+
+	rules, err := p.NATRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rules.Rules[0]
+	if r.SourceTranslationType != "dynamic-ip" || len(r.SourceTranslatedAddresses) != 1 || r.SourceTranslatedAddresses[0] != "pool" || r.Service != "any" {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestEditNATRuleKeepsUnmappedSettings(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") == "get" {
+			return `<response status="success"><result total-count="1" count="1">` + existingNATRule + `</result></response>`
+		}
+
+		return success
+	})
+
+	rule := &NATRule{Name: "outbound", Service: "any", SourceTranslationType: "dynamic-ip", SourceTranslatedAddresses: []string{"pool2"}}
+	if err := p.EditNATRule(rule, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	element := d.sent("edit")[0].Get("element")
+	for _, want := range []string{
+		"<member>pool2</member>",
+		"<fallback><interface-address><interface>ethernet1/1</interface></interface-address></fallback>",
+		"<target><negate>no</negate></target>",
+		"<active-active-device-binding>primary</active-active-device-binding>",
+	} {
+		if !strings.Contains(element, want) {
+			t.Errorf("%s is missing %s", element, want)
+		}
+	}
+
+	// Switching to a different kind of translation drops the settings of the old one.
+	rule.SourceTranslationType = "static-ip"
+	if err := p.EditNATRule(rule, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if element := d.sent("edit")[1].Get("element"); strings.Contains(element, "fallback") {
+		t.Errorf("%s kept the fallback of the old translation", element)
+	}
+}
@@ -1,12 +1,11 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"strings"
-
-	"github.com/scottdware/go-rested"
 )
 
 // URLCategory contains a slice of all custom URL category objects.
@@ -24,27 +23,29 @@ type CustomURL struct {
 	Members     []string `xml:"list>member,omitempty"`
 }
 
-// URLCategory returns a list of all custom URL category objects. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all objects are returned.
-func (p *PaloAlto) URLCategory(devicegroup ...string) (*URLCategory, error) {
+// URLCategory returns a list of all custom URL category objects. You can (optionally) specify a Location as the last
+// parameter, such as a device-group when ran against a Panorama device. If no location is specified, then all
+// objects are returned. When ran against a firewall, you can (optionally) specify a VsysLocation instead.
+func (p *PaloAlto) URLCategory(loc ...Location) (*URLCategory, error) {
+	return p.URLCategoryContext(context.Background(), loc...)
+}
+
+// URLCategoryContext is the same as URLCategory, but uses the given context for all API requests.
+func (p *PaloAlto) URLCategoryContext(ctx context.Context, loc ...Location) (*URLCategory, error) {
 	var urls URLCategory
 	xpath := "/config/devices/entry//custom-url-category"
-	r := rested.NewRequest()
-
-	if p.DeviceType != "panorama" && len(devicegroup) > 0 {
-		return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-	}
 
 	if p.DeviceType == "panos" && p.Panorama == true {
 		xpath = "/config/panorama//custom-url-category"
 	}
 
-	if p.DeviceType == "panos" && p.Panorama == false {
-		xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category"
-	}
+	if (p.DeviceType == "panos" && p.Panorama == false) || len(loc) > 0 {
+		base, err := p.locationXpath(loc)
+		if err != nil {
+			return nil, err
+		}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category", devicegroup[0])
+		xpath = base + "/profiles/custom-url-category"
 	}
 
 	query := map[string]string{
@@ -53,49 +54,56 @@ func (p *PaloAlto) URLCategory(devicegroup ...string) (*URLCategory, error) {
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	urlData := r.Send("get", p.URI, nil, headers, query)
+	urlData := p.send(ctx, "get", query)
+	if urlData.Error != nil {
+		return nil, urlData.Error
+	}
 
 	if err := xml.Unmarshal(urlData.Body, &urls); err != nil {
 		return nil, err
 	}
 
 	if urls.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", urls.Code, errorCodes[urls.Code])
+		return nil, newAPIError(urlData, query)
 	}
 
 	return &urls, nil
 }
 
-// CreateURLCategory creates a custom URL category to be used in a policy. When specifying multiple URL's, separate them
-// using a comma, i.e. "www.*.com, *.sdubs.org". When creating a custom URL category on a Panorama device, specify the
-// device-group as the last parameter.
-func (p *PaloAlto) CreateURLCategory(name, urls, description string, devicegroup ...string) error {
+// CreateURLCategory creates a custom URL category to be used in a policy. When specifying multiple URL's, separate
+// them using a comma, i.e. "www.*.com, *.sdubs.org". When creating a custom URL category on a Panorama device,
+// specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can specify
+// a VsysLocation instead.
+func (p *PaloAlto) CreateURLCategory(name, urls, description string, loc ...Location) error {
+	return p.CreateURLCategoryContext(context.Background(), name, urls, description, loc...)
+}
+
+// CreateURLCategoryContext is the same as CreateURLCategory, but uses the given context for all API requests.
+func (p *PaloAlto) CreateURLCategoryContext(ctx context.Context, name, urls, description string, loc ...Location) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 	u := strings.Split(urls, ",")
 
+	if err := validateName(name); err != nil {
+		return err
+	}
+
 	xmlBody := "<list>"
 	for _, m := range u {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(m))
+		xmlBody += fmt.Sprintf("<member>%s</member>", escapeXML(strings.TrimSpace(m)))
 	}
 	xmlBody += "</list>"
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']", name)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":    "config",
@@ -105,7 +113,7 @@ func (p *PaloAlto) CreateURLCategory(name, urls, description string, devicegroup
 		"element": xmlBody,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -115,62 +123,50 @@ func (p *PaloAlto) CreateURLCategory(name, urls, description string, devicegroup
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
-// EditURLCategory adds or removes URL's from the given custom URL category. Action must be "add" or "remove". When editing a
-// custom URL category on a Panorama device, specify the device-group as the last parameter.
-func (p *PaloAlto) EditURLCategory(action, url, name string, devicegroup ...string) error {
+// EditURLCategory adds or removes URL's from the given custom URL category. Action must be "add" or "remove". When
+// editing a custom URL category on a Panorama device, specify its location (i.e. a DeviceGroupLocation) as the last
+// parameter. On a multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) EditURLCategory(action, url, name string, loc ...Location) error {
+	return p.EditURLCategoryContext(context.Background(), action, url, name, loc...)
+}
+
+// EditURLCategoryContext is the same as EditURLCategory, but uses the given context for all API requests.
+func (p *PaloAlto) EditURLCategoryContext(ctx context.Context, action, url, name string, loc ...Location) error {
 	var xpath string
 	var xmlBody string
 	var reqError requestError
-	r := rested.NewRequest()
 
 	query := map[string]string{
 		"type": "config",
 		"key":  p.Key,
 	}
 
-	if p.DeviceType == "panos" {
-		if action == "add" {
-			xmlBody += fmt.Sprintf("<member>%s</member>", url)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']/list", name)
-			query["action"] = "set"
-			query["element"] = xmlBody
-			query["xpath"] = xpath
-		}
-
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']/list/member[text()='%s']", name, url)
-			query["action"] = "delete"
-			query["xpath"] = xpath
-		}
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", url)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']/list", devicegroup[0], name)
-			query["action"] = "set"
-			query["element"] = xmlBody
-			query["xpath"] = xpath
-		}
-
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']/list/member[text()='%s']", devicegroup[0], name, url)
-			query["action"] = "delete"
-			query["xpath"] = xpath
-		}
+	if action == "add" {
+		xmlBody = fmt.Sprintf("<member>%s</member>", escapeXML(url))
+		xpath = fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]/list", base, xpathLiteral(name))
+		query["action"] = "set"
+		query["element"] = xmlBody
+		query["xpath"] = xpath
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
+	if action == "remove" {
+		xpath = fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]/list/member[text()=%s]", base, xpathLiteral(name), xpathLiteral(url))
+		query["action"] = "delete"
+		query["xpath"] = xpath
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -180,30 +176,30 @@ func (p *PaloAlto) EditURLCategory(action, url, name string, devicegroup ...stri
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
-// DeleteURLCategory removes a custom URL category from the device. When removing a custom URL category on a Panorama device, specify the
-// device-group as the last parameter.
-func (p *PaloAlto) DeleteURLCategory(name string, devicegroup ...string) error {
+// DeleteURLCategory removes a custom URL category from the device. When removing a custom URL category on a Panorama
+// device, specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can
+// specify a VsysLocation instead.
+func (p *PaloAlto) DeleteURLCategory(name string, loc ...Location) error {
+	return p.DeleteURLCategoryContext(context.Background(), name, loc...)
+}
+
+// DeleteURLCategoryContext is the same as DeleteURLCategory, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteURLCategoryContext(ctx context.Context, name string, loc ...Location) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/profiles/custom-url-category/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/profiles/custom-url-category/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":   "config",
@@ -212,7 +208,7 @@ func (p *PaloAlto) DeleteURLCategory(name string, devicegroup ...string) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -222,74 +218,56 @@ func (p *PaloAlto) DeleteURLCategory(name string, devicegroup ...string) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
 // EditGroup will add or remove objects from the specified group type (i.e., "address" or "service"). Action must be
-// "add" or "remove". When editing a group on a Panorama device, you must specify the device-group as the last parameter.
-func (p *PaloAlto) EditGroup(objecttype, action, object, group string, devicegroup ...string) error {
+// "add" or "remove". When editing a group on a Panorama device, you must specify its location (i.e. a
+// DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) EditGroup(objecttype, action, object, group string, loc ...Location) error {
+	return p.EditGroupContext(context.Background(), objecttype, action, object, group, loc...)
+}
+
+// EditGroupContext is the same as EditGroup, but uses the given context for all API requests.
+func (p *PaloAlto) EditGroupContext(ctx context.Context, objecttype, action, object, group string, loc ...Location) error {
 	var xmlBody string
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 
 	query := map[string]string{
 		"type": "config",
 		"key":  p.Key,
 	}
 
-	if p.DeviceType == "panos" {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", object)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/static", group)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/members", group)
-			}
-			query["action"] = "set"
-			query["element"] = xmlBody
-			query["xpath"] = xpath
-		}
-
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/static/member[text()='%s']", group, object)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/members/member[text()='%s']", group, object)
-			}
-			query["action"] = "delete"
-			query["xpath"] = xpath
-		}
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		if action == "add" {
-			xmlBody = fmt.Sprintf("<member>%s</member>", object)
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/static", devicegroup[0], group)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/members", devicegroup[0], group)
-			}
-			query["action"] = "set"
-			query["element"] = xmlBody
-			query["xpath"] = xpath
-		}
-
-		if action == "remove" {
-			xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/static/member[text()='%s']", devicegroup[0], group, object)
-			if objecttype == "service" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/members/member[text()='%s']", devicegroup[0], group, object)
-			}
-			query["action"] = "delete"
-			query["xpath"] = xpath
+	if action == "add" {
+		xmlBody = fmt.Sprintf("<member>%s</member>", escapeXML(object))
+		xpath = fmt.Sprintf("%s/address-group/entry[@name=%s]/static", base, xpathLiteral(group))
+		if objecttype == "service" {
+			xpath = fmt.Sprintf("%s/service-group/entry[@name=%s]/members", base, xpathLiteral(group))
 		}
+		query["action"] = "set"
+		query["element"] = xmlBody
+		query["xpath"] = xpath
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
+	if action == "remove" {
+		xpath = fmt.Sprintf("%s/address-group/entry[@name=%s]/static/member[text()=%s]", base, xpathLiteral(group), xpathLiteral(object))
+		if objecttype == "service" {
+			xpath = fmt.Sprintf("%s/service-group/entry[@name=%s]/members/member[text()=%s]", base, xpathLiteral(group), xpathLiteral(object))
+		}
+		query["action"] = "delete"
+		query["xpath"] = xpath
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -299,281 +277,163 @@ func (p *PaloAlto) EditGroup(objecttype, action, object, group string, devicegro
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
 // RenameObject will rename the given object from it's 'oldname' to the 'newname.' You can rename the following
-// object types: address, address-groups, service, service-groups, tags. When renaming an object
-// on a Panorama device, you must specify the device-group as the last parameter.
-func (p *PaloAlto) RenameObject(oldname, newname string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
-	adObj, _ := p.Addresses()
-	agObj, _ := p.AddressGroups()
-	sObj, _ := p.Services()
-	sgObj, _ := p.ServiceGroups()
-	tags, _ := p.Tags()
-
-	query := map[string]string{
-		"type":    "config",
-		"action":  "rename",
-		"key":     p.Key,
-		"newname": newname,
-	}
-
-	for _, a := range adObj.Addresses {
-		if oldname == a.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']", oldname)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+// object types: address, address-groups, service, service-groups, tags, which are looked for in that order - use
+// RenameObjectByType to rename other types of objects, or to skip the lookups. When renaming an object on a Panorama
+// device, you must specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys
+// firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) RenameObject(oldname, newname string, loc ...Location) error {
+	return p.RenameObjectContext(context.Background(), oldname, newname, loc...)
+}
 
-				return nil
-			}
+// RenameObjectContext is the same as RenameObject, but uses the given context for all API requests.
+func (p *PaloAlto) RenameObjectContext(ctx context.Context, oldname, newname string, loc ...Location) error {
+	validate := validateName
 
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']", devicegroup[0], oldname)
+	objecttype, xpath, err := p.findObject(ctx, oldname, []string{"address", "address-group", "service", "service-group", "tag"}, loc)
+	if err != nil {
+		return err
+	}
 
-				query["xpath"] = xpath
+	if objecttype == "tag" {
+		validate = validateTagName
+	}
 
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
+	if err := validate(newname); err != nil {
+		return err
+	}
 
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
+	return p.ConfigRenameContext(ctx, xpath, newname)
+}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+// RenameObjectByType is the same as RenameObject, but renames the object of the given type. objecttype should be
+// one of: address, address-group, service, service-group, tag, url-category, security-rule, nat-rule or zone.
+func (p *PaloAlto) RenameObjectByType(objecttype, oldname, newname string, loc ...Location) error {
+	return p.RenameObjectByTypeContext(context.Background(), objecttype, oldname, newname, loc...)
+}
 
-				return nil
-			}
+// RenameObjectByTypeContext is the same as RenameObjectByType, but uses the given context for all API requests.
+func (p *PaloAlto) RenameObjectByTypeContext(ctx context.Context, objecttype, oldname, newname string, loc ...Location) error {
+	validate := validateName
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
+	if objecttype == "tag" {
+		validate = validateTagName
 	}
 
-	for _, ag := range agObj.Groups {
-		if oldname == ag.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']", oldname)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
+	if err := validate(newname); err != nil {
+		return err
+	}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	xpath, err := p.objectXpath(ctx, objecttype, oldname, loc)
+	if err != nil {
+		return err
+	}
 
-				return nil
-			}
+	return p.ConfigRenameContext(ctx, xpath, newname)
+}
 
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']", devicegroup[0], oldname)
+// objectXpath returns the xpath of the object with the given type and name in the location. Rules on a Panorama
+// device are looked for in the pre-rulebase, and then the post-rulebase.
+func (p *PaloAlto) objectXpath(ctx context.Context, objecttype, name string, loc []Location) (string, error) {
+	if name == "" {
+		return "", errors.New("you must specify the name of the object")
+	}
 
-				query["xpath"] = xpath
+	l, err := p.location(loc)
+	if err != nil {
+		return "", err
+	}
 
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
+	base, err := l.Xpath()
+	if err != nil {
+		return "", err
+	}
 
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
+	switch objecttype {
+	case "address", "address-group", "service", "service-group", "tag":
+		return fmt.Sprintf("%s/%s/entry[@name=%s]", base, objecttype, xpathLiteral(name)), nil
+	case "url-category":
+		return fmt.Sprintf("%s/profiles/custom-url-category/entry[@name=%s]", base, xpathLiteral(name)), nil
+	case "zone":
+		if l.Shared || l.DeviceGroup != "" {
+			return "", errors.New("zones can only be in a vsys, or a vsys within a template or template-stack")
+		}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+		return fmt.Sprintf("%s/zone/entry[@name=%s]", base, xpathLiteral(name)), nil
+	case "security-rule", "nat-rule":
+		ruletype := strings.TrimSuffix(objecttype, "-rule")
 
-				return nil
+		if p.DeviceType == "panos" {
+			rules, err := p.rulebaseXpath(ruletype, "", loc)
+			if err != nil {
+				return "", err
 			}
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
+			return fmt.Sprintf("%s/entry[@name=%s]", rules, xpathLiteral(name)), nil
 		}
-	}
-
-	for _, s := range sObj.Services {
-		if oldname == s.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']", oldname)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
 
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
+		for _, rulebase := range []string{"pre", "post"} {
+			rules, err := p.rulebaseXpath(ruletype, rulebase, loc)
+			if err != nil {
+				return "", err
 			}
 
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']", devicegroup[0], oldname)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
+			xpath := fmt.Sprintf("%s/entry[@name=%s]", rules, xpathLiteral(name))
 
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
+			found, err := p.configExists(ctx, xpath)
+			if err != nil {
+				return "", err
 			}
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
+			if found {
+				return xpath, nil
 			}
 		}
-	}
-
-	for _, sg := range sgObj.Groups {
-		if oldname == sg.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']", oldname)
 
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']", devicegroup[0], oldname)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
+		return "", fmt.Errorf("%s rule %s was not found in the pre or post rulebase", ruletype, name)
+	}
 
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
+	return "", fmt.Errorf("unknown object type %s, should be one of: address, address-group, service, service-group, tag, url-category, security-rule, nat-rule or zone", objecttype)
+}
 
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+// findObject returns the type and xpath of the first object with the given name, out of the given object types, in
+// the location. Each type is looked up with a single request, in order, until the object is found.
+func (p *PaloAlto) findObject(ctx context.Context, name string, types []string, loc []Location) (string, string, error) {
+	for _, t := range types {
+		xpath, err := p.objectXpath(ctx, t, name, loc)
+		if err != nil {
+			return "", "", err
+		}
 
-				return nil
-			}
+		found, err := p.configExists(ctx, xpath)
+		if err != nil {
+			return "", "", err
+		}
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
+		if found {
+			return t, xpath, nil
 		}
 	}
 
-	for _, t := range tags.Tags {
-		if oldname == t.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/tag/entry[@name='%s']", oldname)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/tag/entry[@name='%s']", devicegroup[0], oldname)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	return "", "", fmt.Errorf("no %s named %s was found", strings.Join(types, ", "), name)
+}
 
-				return nil
-			}
+// configExists returns true if there is anything at the given xpath in the candidate configuration.
+func (p *PaloAlto) configExists(ctx context.Context, xpath string) (bool, error) {
+	data, err := p.ConfigGetContext(ctx, xpath)
+	if IsNotFound(err) {
+		return false, nil
+	}
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return len(data) > 0, nil
 }
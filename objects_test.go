@@ -0,0 +1,72 @@
+package panos
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRenameObjectLookup(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") == "get" && !strings.Contains(q.Get("xpath"), "/service-group/") {
+			return `<response status="error" code="7"><msg><line>No such node</line></msg></response>`
+		}
+
+		if q.Get("action") == "get" {
+			return `<response status="success"><result><entry name="web"/></result></response>`
+		}
+
+		return success
+	})
+
+	if err := p.RenameObject("web", "web-ports"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(d.sent("get")); n != 4 {
+		t.Errorf("sent %d lookups, want 4", n)
+	}
+
+	q := d.sent("rename")[0]
+	if !strings.HasSuffix(q.Get("xpath"), "/vsys/entry[@name='vsys1']/service-group/entry[@name='web']") || q.Get("newname") != "web-ports" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
+
+func TestRenameObjectNotFound(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result/></response>`
+	})
+
+	if err := p.RenameObject("web", "web-ports"); err == nil || !strings.Contains(err.Error(), "no address, address-group, service, service-group, tag named web") {
+		t.Errorf("err = %v", err)
+	}
+
+	if len(d.sent("rename")) != 0 {
+		t.Error("a missing object should not be renamed")
+	}
+}
+
+func TestRenameObjectByType(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	if err := p.RenameObjectByType("tag", "prod", "prod / live"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.RenameObjectByType("address", "web", "web/1"); err == nil {
+		t.Error("expected an error for an invalid object name")
+	}
+
+	if err := p.RenameObjectByType("zone", "trust", "inside", SharedLocation()); err == nil {
+		t.Error("expected an error for a shared zone")
+	}
+
+	if err := p.RenameObjectByType("widget", "a", "b"); err == nil {
+		t.Error("expected an error for an unknown object type")
+	}
+
+	if len(d.sent("")) != 1 || len(d.sent("get")) != 0 {
+		t.Errorf("unexpected requests %v", d.sent(""))
+	}
+}
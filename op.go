@@ -0,0 +1,195 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// opResult is used for parsing the result of an operational command.
+type opResult struct {
+	XMLName xml.Name `xml:"response"`
+	Status  string   `xml:"status,attr"`
+	Code    string   `xml:"code,attr"`
+	Result  struct {
+		Data []byte `xml:",innerxml"`
+	} `xml:"result"`
+}
+
+// cliToXML converts a CLI-style command into the nested XML form used by the API. Each word becomes an element
+// nested inside the one before it, and quoted values become the text of the previous element, i.e.
+// show jobs id "4" becomes <show><jobs><id>4</id></jobs></show>. Unquoted words must be valid element names.
+func cliToXML(cmd string) (string, error) {
+	var b bytes.Buffer
+	var stack []string
+	var word bytes.Buffer
+	inQuote := false
+	quoted := false
+
+	flush := func() error {
+		if word.Len() <= 0 && !quoted {
+			return nil
+		}
+
+		if quoted {
+			if len(stack) > 0 {
+				xml.EscapeText(&b, word.Bytes())
+				b.WriteString("</" + stack[len(stack)-1] + ">")
+				stack = stack[:len(stack)-1]
+			}
+		} else {
+			if !isElementName(word.String()) {
+				return fmt.Errorf("%s is not a valid command keyword, put values in double quotes", word.String())
+			}
+
+			b.WriteString("<" + word.String() + ">")
+			stack = append(stack, word.String())
+		}
+
+		word.Reset()
+		quoted = false
+
+		return nil
+	}
+
+	for _, c := range cmd {
+		switch {
+		case c == '"':
+			if inQuote {
+				quoted = true
+			} else if err := flush(); err != nil {
+				return "", err
+			}
+
+			inQuote = !inQuote
+		case !inQuote && (c == ' ' || c == '\t' || c == '\n'):
+			if err := flush(); err != nil {
+				return "", err
+			}
+		default:
+			word.WriteRune(c)
+		}
+	}
+
+	if inQuote {
+		return "", errors.New("the command contains an unterminated quote")
+	}
+
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	if len(stack) <= 0 && b.Len() <= 0 {
+		return "", errors.New("you must specify a command")
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteString("</" + stack[i] + ">")
+	}
+
+	return b.String(), nil
+}
+
+// isElementName returns true if the given word can be used as the name of an XML element. It must start with a
+// letter or an underscore, followed by letters, digits, hyphens, underscores or periods.
+func isElementName(word string) bool {
+	for i, c := range word {
+		switch {
+		case unicode.IsLetter(c) || c == '_':
+		case i > 0 && (unicode.IsDigit(c) || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+
+	return word != ""
+}
+
+// opCommand returns the command in XML form, converting it from a CLI-style command if needed.
+func opCommand(cmd string) (string, error) {
+	cmd = strings.TrimSpace(cmd)
+
+	if strings.HasPrefix(cmd, "<") {
+		return cmd, nil
+	}
+
+	return cliToXML(cmd)
+}
+
+// Op runs an operational command on the device, and returns the XML contents of the result. The command can be
+// written in XML, i.e. "<show><system><resources></resources></system></show>", or the same way as on the CLI,
+// i.e. "show system resources". When using the CLI form, put any values in double quotes, i.e. show jobs id "4".
+func (p *PaloAlto) Op(cmd string) ([]byte, error) {
+	return p.OpContext(context.Background(), cmd)
+}
+
+// OpContext is the same as Op, but uses the given context for all API requests.
+func (p *PaloAlto) OpContext(ctx context.Context, cmd string) ([]byte, error) {
+	var result opResult
+
+	resp, query, err := p.op(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := xml.Unmarshal(resp.Body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Status != "success" {
+		return nil, newAPIError(resp, query)
+	}
+
+	return bytes.TrimSpace(result.Result.Data), nil
+}
+
+// OpInto runs an operational command on the device, just like Op, and parses the entire response into v. The
+// struct should start at the <response> element, i.e. a field tagged `xml:"result>system>hostname"`.
+func (p *PaloAlto) OpInto(cmd string, v interface{}) error {
+	return p.OpIntoContext(context.Background(), cmd, v)
+}
+
+// OpIntoContext is the same as OpInto, but uses the given context for all API requests.
+func (p *PaloAlto) OpIntoContext(ctx context.Context, cmd string, v interface{}) error {
+	var reqError requestError
+
+	resp, query, err := p.op(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+		return err
+	}
+
+	if reqError.Status != "success" {
+		return newAPIError(resp, query)
+	}
+
+	return xml.Unmarshal(resp.Body, v)
+}
+
+// op sends the operational command, and returns the response along with the query that was sent.
+func (p *PaloAlto) op(ctx context.Context, cmd string) (*apiResponse, map[string]string, error) {
+	xmlCmd, err := opCommand(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  xmlCmd,
+		"key":  p.Key,
+	}
+
+	resp := p.send(ctx, "get", query)
+	if resp.Error != nil {
+		return nil, nil, resp.Error
+	}
+
+	return resp, query, nil
+}
@@ -0,0 +1,77 @@
+package panos
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCLIToXML(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+		ok   bool
+	}{
+		{"show system info", "<show><system><info></info></system></show>", true},
+		{`show jobs id "4"`, "<show><jobs><id>4</id></jobs></show>", true},
+		{"  show   clock\n", "<show><clock></clock></show>", true},
+		{`test security-policy-match from "trust zone" destination "10.1.1.1"`, "<test><security-policy-match><from>trust zone</from><destination>10.1.1.1</destination></security-policy-match></test>", true},
+		{`show user ip-user-mapping ip "a<b&c"`, "<show><user><ip-user-mapping><ip>a&lt;b&amp;c</ip></ip-user-mapping></user></show>", true},
+		{`request restart system ""`, "<request><restart><system></system></restart></request>", true},
+		{`show jobs id "4`, "", false},
+		{"show jobs id 4", "", false},
+		{"show <system> info", "", false},
+		{"show a&b", "", false},
+		{"show -jobs", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := cliToXML(tt.cmd)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: err = %v", tt.cmd, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestOpCommandXML(t *testing.T) {
+	cmd := "  <show><system><resources></resources></system></show>"
+
+	got, err := opCommand(cmd)
+	if err != nil || got != "<show><system><resources></resources></system></show>" {
+		t.Errorf("got %s, %v", got, err)
+	}
+}
+
+func TestOp(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result>
+			<system><hostname>fw1</hostname></system>
+		</result></response>`
+	})
+
+	data, err := p.Op("show system info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "<system><hostname>fw1</hostname></system>" {
+		t.Errorf("got %s", data)
+	}
+
+	var info struct {
+		Hostname string `xml:"result>system>hostname"`
+	}
+
+	if err := p.OpInto("show system info", &info); err != nil || info.Hostname != "fw1" {
+		t.Errorf("got %+v, %v", info, err)
+	}
+
+	if cmd := d.sent("op")[0].Get("cmd"); cmd != "<show><system><info></info></system></show>" {
+		t.Errorf("unexpected command %s", cmd)
+	}
+}
@@ -2,15 +2,15 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/scottdware/go-rested"
 )
 
 // PaloAlto is a container for our session state.
@@ -24,6 +24,8 @@ type PaloAlto struct {
 	SoftwareVersion string
 	DeviceType      string
 	Panorama        bool
+	client          *http.Client
+	vsys            string
 }
 
 // Devices lists all of the devices in Panorama.
@@ -53,6 +55,31 @@ type Serial struct {
 	Serial string `xml:"name,attr"`
 }
 
+// VirtualSystems contains a slice of all virtual systems (vsys) on a firewall.
+type VirtualSystems struct {
+	Vsys []VirtualSystem
+}
+
+// VirtualSystem contains information about each individual vsys.
+type VirtualSystem struct {
+	Name        string
+	DisplayName string
+}
+
+// xmlVirtualSystems is used for parsing all virtual systems.
+type xmlVirtualSystems struct {
+	XMLName xml.Name           `xml:"response"`
+	Status  string             `xml:"status,attr"`
+	Code    string             `xml:"code,attr"`
+	Vsys    []xmlVirtualSystem `xml:"result>vsys>entry"`
+}
+
+// xmlVirtualSystem is used for parsing each individual vsys.
+type xmlVirtualSystem struct {
+	Name        string `xml:"name,attr"`
+	DisplayName string `xml:"display-name,omitempty"`
+}
+
 // Tags contains information about all tags on the system.
 type Tags struct {
 	Tags []Tag
@@ -109,17 +136,14 @@ type panoramaStatus struct {
 
 // requestError contains information about any error we get from a request.
 type requestError struct {
-	XMLName xml.Name `xml:"response"`
-	Status  string   `xml:"status,attr"`
-	Code    string   `xml:"code,attr"`
-	Message string   `xml:"result>msg,omitempty"`
+	XMLName       xml.Name     `xml:"response"`
+	Status        string       `xml:"status,attr"`
+	Code          string       `xml:"code,attr"`
+	Message       errorMessage `xml:"msg,omitempty"`
+	ResultMessage errorMessage `xml:"result>msg,omitempty"`
 }
 
 var (
-	headers = map[string]string{
-		"Content-Type": "application/xml",
-	}
-
 	tagColors = map[string]string{
 		"Red":         "color1",
 		"Green":       "color2",
@@ -167,10 +191,16 @@ var (
 	}
 )
 
-// splitSWVersion
+// splitSWVersion returns the major, minor and release numbers of the given software version. If the version can't be
+// parsed, such as when SoftwareVersion is empty, then 0.0.0 is returned and callers treat the device as the oldest
+// version they support.
 func splitSWVersion(version string) []int {
 	re := regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
 	match := re.FindStringSubmatch(version)
+	if match == nil {
+		return []int{0, 0, 0}
+	}
+
 	maj, _ := strconv.Atoi(match[1])
 	min, _ := strconv.Atoi(match[2])
 	rel, _ := strconv.Atoi(match[3])
@@ -178,16 +208,42 @@ func splitSWVersion(version string) []int {
 	return []int{maj, min, rel}
 }
 
-// NewSession sets up our connection to the Palo Alto firewall or Panorama device.
-func NewSession(host, user, passwd string) (*PaloAlto, error) {
+// NewSession sets up our connection to the Palo Alto firewall or Panorama device. You can (optionally) specify
+// one or more SessionOption's to control how the device is reached, such as a custom HTTP client, CA certificate
+// or timeout.
+func NewSession(host, user, passwd string, options ...SessionOption) (*PaloAlto, error) {
+	return NewSessionContext(context.Background(), host, user, passwd, options...)
+}
+
+// NewSessionContext is the same as NewSession, but uses the given context for all API requests.
+func NewSessionContext(ctx context.Context, host, user, passwd string, options ...SessionOption) (*PaloAlto, error) {
 	var key authKey
 	var info systemInfo
 	var pan panoramaStatus
+	var config sessionConfig
 	status := false
 	deviceType := "panos"
-	r := rested.NewRequest()
 
-	resp := r.Send("get", fmt.Sprintf("https://%s/api/?type=keygen&user=%s&password=%s", host, user, passwd), nil, nil, nil)
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &PaloAlto{
+		Host:   host,
+		URI:    fmt.Sprintf("https://%s/api/?", host),
+		client: newHTTPClient(&config),
+		vsys:   config.vsys,
+	}
+
+	keyQuery := map[string]string{
+		"type":     "keygen",
+		"user":     user,
+		"password": passwd,
+	}
+
+	resp := p.send(ctx, "post", keyQuery)
 	if resp.Error != nil {
 		return nil, resp.Error
 	}
@@ -198,32 +254,27 @@ func NewSession(host, user, passwd string) (*PaloAlto, error) {
 	}
 
 	if key.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s (keygen)", key.Code, errorCodes[key.Code])
+		return nil, newAPIError(resp, keyQuery)
 	}
 
-	uri := fmt.Sprintf("https://%s/api/?", host)
-	getInfo := r.Send("get", fmt.Sprintf("%s&key=%s&type=op&cmd=<show><system><info></info></system></show>", uri, key.Key), nil, nil, nil)
-
-	if getInfo.Error != nil {
-		return nil, getInfo.Error
-	}
+	p.Key = key.Key
 
-	err = xml.Unmarshal(getInfo.Body, &info)
-	if err != nil {
+	if err := p.OpIntoContext(ctx, "show system info", &info); err != nil {
 		return nil, err
 	}
 
-	if info.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s (show system info)", info.Code, errorCodes[info.Code])
+	panQuery := map[string]string{
+		"type": "op",
+		"cmd":  "<show><panorama-status></panorama-status></show>",
+		"key":  p.Key,
 	}
 
-	panStatus := r.Send("get", fmt.Sprintf("%s&key=%s&type=op&cmd=<show><panorama-status></panorama-status></show>", uri, key.Key), nil, nil, nil)
+	panStatus := p.send(ctx, "get", panQuery)
 	if panStatus.Error != nil {
 		return nil, panStatus.Error
 	}
 
-	err = xml.Unmarshal(panStatus.Body, &pan)
-	if err != nil {
+	if err := xml.Unmarshal(panStatus.Body, &pan); err != nil {
 		return nil, err
 	}
 
@@ -235,25 +286,26 @@ func NewSession(host, user, passwd string) (*PaloAlto, error) {
 		status = true
 	}
 
-	return &PaloAlto{
-		Host:            host,
-		Key:             key.Key,
-		URI:             fmt.Sprintf("https://%s/api/?", host),
-		Platform:        info.Platform,
-		Model:           info.Model,
-		Serial:          info.Serial,
-		SoftwareVersion: info.SoftwareVersion,
-		DeviceType:      deviceType,
-		Panorama:        status,
-	}, nil
+	p.Platform = info.Platform
+	p.Model = info.Model
+	p.Serial = info.Serial
+	p.SoftwareVersion = info.SoftwareVersion
+	p.DeviceType = deviceType
+	p.Panorama = status
+
+	return p, nil
 }
 
 // Devices returns information about all of the devices that are managed by Panorama.
 func (p *PaloAlto) Devices() (*Devices, error) {
+	return p.DevicesContext(context.Background())
+}
+
+// DevicesContext is the same as Devices, but uses the given context for all API requests.
+func (p *PaloAlto) DevicesContext(ctx context.Context) (*Devices, error) {
 	var devices Devices
 	xpath := "/config/mgt-config/devices"
 	// xpath := "/config/devices/entry/vsys/entry/address"
-	r := rested.NewRequest()
 
 	if p.DeviceType != "panorama" {
 		return nil, errors.New("devices can only be listed from a Panorama device")
@@ -265,14 +317,17 @@ func (p *PaloAlto) Devices() (*Devices, error) {
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	devData := r.Send("get", p.URI, nil, headers, query)
+	devData := p.send(ctx, "get", query)
+	if devData.Error != nil {
+		return nil, devData.Error
+	}
 
 	if err := xml.Unmarshal(devData.Body, &devices); err != nil {
 		return nil, err
 	}
 
 	if devices.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", devices.Code, errorCodes[devices.Code])
+		return nil, newAPIError(devData, query)
 	}
 
 	return &devices, nil
@@ -281,10 +336,14 @@ func (p *PaloAlto) Devices() (*Devices, error) {
 // DeviceGroups returns information about all of the device-groups in Panorama, and what devices are
 // linked to them.
 func (p *PaloAlto) DeviceGroups() (*DeviceGroups, error) {
+	return p.DeviceGroupsContext(context.Background())
+}
+
+// DeviceGroupsContext is the same as DeviceGroups, but uses the given context for all API requests.
+func (p *PaloAlto) DeviceGroupsContext(ctx context.Context) (*DeviceGroups, error) {
 	var devices DeviceGroups
 	xpath := "/config/devices/entry//device-group"
 	// xpath := "/config/devices/entry/vsys/entry/address"
-	r := rested.NewRequest()
 
 	if p.DeviceType != "panorama" {
 		return nil, errors.New("device-groups can only be listed from a Panorama device")
@@ -296,26 +355,78 @@ func (p *PaloAlto) DeviceGroups() (*DeviceGroups, error) {
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	devData := r.Send("get", p.URI, nil, headers, query)
+	devData := p.send(ctx, "get", query)
+	if devData.Error != nil {
+		return nil, devData.Error
+	}
 
 	if err := xml.Unmarshal(devData.Body, &devices); err != nil {
 		return nil, err
 	}
 
 	if devices.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", devices.Code, errorCodes[devices.Code])
+		return nil, newAPIError(devData, query)
 	}
 
 	return &devices, nil
 }
 
+// Vsys returns information about all of the virtual systems (vsys) on a firewall.
+func (p *PaloAlto) Vsys() (*VirtualSystems, error) {
+	return p.VsysContext(context.Background())
+}
+
+// VsysContext is the same as Vsys, but uses the given context for all API requests.
+func (p *PaloAlto) VsysContext(ctx context.Context) (*VirtualSystems, error) {
+	var parsedVsys xmlVirtualSystems
+	var vsys VirtualSystems
+	xpath := "/config/devices/entry[@name='localhost.localdomain']/vsys"
+
+	if p.DeviceType == "panorama" {
+		return nil, errors.New("vsys can only be listed from a firewall")
+	}
+
+	query := map[string]string{
+		"type":   "config",
+		"action": "get",
+		"xpath":  xpath,
+		"key":    p.Key,
+	}
+	vsysData := p.send(ctx, "get", query)
+	if vsysData.Error != nil {
+		return nil, vsysData.Error
+	}
+
+	if err := xml.Unmarshal(vsysData.Body, &parsedVsys); err != nil {
+		return nil, err
+	}
+
+	if parsedVsys.Status != "success" {
+		return nil, newAPIError(vsysData, query)
+	}
+
+	for _, v := range parsedVsys.Vsys {
+		vsys.Vsys = append(vsys.Vsys, VirtualSystem{Name: v.Name, DisplayName: v.DisplayName})
+	}
+
+	return &vsys, nil
+}
+
 // CreateDeviceGroup will create a new device-group on a Panorama device. You can add devices as well by
 // specifying the serial numbers in a string slice ([]string). Use 'nil' if you do not wish to add any.
 func (p *PaloAlto) CreateDeviceGroup(name, description string, devices []string) error {
+	return p.CreateDeviceGroupContext(context.Background(), name, description, devices)
+}
+
+// CreateDeviceGroupContext is the same as CreateDeviceGroup, but uses the given context for all API requests.
+func (p *PaloAlto) CreateDeviceGroupContext(ctx context.Context, name, description string, devices []string) error {
 	var xmlBody string
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
+
+	if err := validateName(name); err != nil {
+		return err
+	}
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to a Panorama device when creating a device-group")
@@ -323,19 +434,19 @@ func (p *PaloAlto) CreateDeviceGroup(name, description string, devices []string)
 
 	if p.DeviceType == "panorama" {
 		xpath = "/config/devices/entry[@name='localhost.localdomain']/device-group"
-		xmlBody = fmt.Sprintf("<entry name=\"%s\">", name)
+		xmlBody = fmt.Sprintf("<entry name=\"%s\">", escapeXML(name))
 	}
 
 	if devices != nil {
 		xmlBody += "<devices>"
 		for _, s := range devices {
-			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(s))
+			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", escapeXML(strings.TrimSpace(s)))
 		}
 		xmlBody += "</devices>"
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
 	xmlBody += "</entry>"
@@ -348,7 +459,7 @@ func (p *PaloAlto) CreateDeviceGroup(name, description string, devices []string)
 		"key":     p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -358,7 +469,7 @@ func (p *PaloAlto) CreateDeviceGroup(name, description string, devices []string)
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -366,16 +477,20 @@ func (p *PaloAlto) CreateDeviceGroup(name, description string, devices []string)
 
 // DeleteDeviceGroup will delete the given device-group from Panorama.
 func (p *PaloAlto) DeleteDeviceGroup(name string) error {
+	return p.DeleteDeviceGroupContext(context.Background(), name)
+}
+
+// DeleteDeviceGroupContext is the same as DeleteDeviceGroup, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteDeviceGroupContext(ctx context.Context, name string) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to a Panorama device when deleting a device-group")
 	}
 
 	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']", name)
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]", xpathLiteral(name))
 	}
 
 	query := map[string]string{
@@ -385,7 +500,7 @@ func (p *PaloAlto) DeleteDeviceGroup(name string) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -395,7 +510,7 @@ func (p *PaloAlto) DeleteDeviceGroup(name string) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -404,8 +519,12 @@ func (p *PaloAlto) DeleteDeviceGroup(name string) error {
 // AddDevice will add a new device to a Panorama. If you specify the optional 'devicegroup' parameter,
 // it will also add the device to the given device-group.
 func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
+	return p.AddDeviceContext(context.Background(), serial, devicegroup...)
+}
+
+// AddDeviceContext is the same as AddDevice, but uses the given context for all API requests.
+func (p *PaloAlto) AddDeviceContext(ctx context.Context, serial string, devicegroup ...string) error {
 	var reqError requestError
-	r := rested.NewRequest()
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to Panorama when adding devices")
@@ -413,7 +532,7 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 
 	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
 		xpath := "/config/mgt-config/devices"
-		xmlBody := fmt.Sprintf("<entry name=\"%s\"/>", serial)
+		xmlBody := fmt.Sprintf("<entry name=\"%s\"/>", escapeXML(serial))
 
 		query := map[string]string{
 			"type":    "config",
@@ -423,7 +542,7 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 			"key":     p.Key,
 		}
 
-		resp := r.Send("post", p.URI, nil, nil, query)
+		resp := p.send(ctx, "post", query)
 		if resp.Error != nil {
 			return resp.Error
 		}
@@ -433,15 +552,15 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 		}
 
 		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+			return newAPIError(resp, query)
 		}
 	}
 
 	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
 		deviceXpath := "/config/mgt-config/devices"
-		deviceXMLBody := fmt.Sprintf("<entry name=\"%s\"/>", serial)
-		xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']", devicegroup[0])
-		xmlBody := fmt.Sprintf("<devices><entry name=\"%s\"/></devices>", serial)
+		deviceXMLBody := fmt.Sprintf("<entry name=\"%s\"/>", escapeXML(serial))
+		xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]", xpathLiteral(devicegroup[0]))
+		xmlBody := fmt.Sprintf("<devices><entry name=\"%s\"/></devices>", escapeXML(serial))
 
 		deviceQuery := map[string]string{
 			"type":    "config",
@@ -451,7 +570,7 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 			"key":     p.Key,
 		}
 
-		addResp := r.Send("post", p.URI, nil, nil, deviceQuery)
+		addResp := p.send(ctx, "post", deviceQuery)
 		if addResp.Error != nil {
 			return addResp.Error
 		}
@@ -461,7 +580,7 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 		}
 
 		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+			return newAPIError(addResp, deviceQuery)
 		}
 
 		time.Sleep(200 * time.Millisecond)
@@ -474,7 +593,7 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 			"key":     p.Key,
 		}
 
-		resp := r.Send("post", p.URI, nil, nil, query)
+		resp := p.send(ctx, "post", query)
 		if resp.Error != nil {
 			return resp.Error
 		}
@@ -484,7 +603,7 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 		}
 
 		if reqError.Status != "success" {
-			return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+			return newAPIError(resp, query)
 		}
 	}
 
@@ -493,10 +612,14 @@ func (p *PaloAlto) AddDevice(serial string, devicegroup ...string) error {
 
 // SetPanoramaServer will configure a device to be managed by the given Panorama server's IP address.
 func (p *PaloAlto) SetPanoramaServer(ip string) error {
+	return p.SetPanoramaServerContext(context.Background(), ip)
+}
+
+// SetPanoramaServerContext is the same as SetPanoramaServer, but uses the given context for all API requests.
+func (p *PaloAlto) SetPanoramaServerContext(ctx context.Context, ip string) error {
 	var reqError requestError
-	r := rested.NewRequest()
 	xpath := "/config/devices/entry[@name='localhost.localdomain']/deviceconfig/system"
-	xmlBody := fmt.Sprintf("<panorama-server>%s</panorama-server>", ip)
+	xmlBody := fmt.Sprintf("<panorama-server>%s</panorama-server>", escapeXML(ip))
 
 	if p.DeviceType == "panorama" && p.Panorama == true {
 		return errors.New("you must be connected to a non-Panorama device in order to configure a Panorama server")
@@ -510,7 +633,7 @@ func (p *PaloAlto) SetPanoramaServer(ip string) error {
 		"element": xmlBody,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -520,7 +643,7 @@ func (p *PaloAlto) SetPanoramaServer(ip string) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -529,20 +652,24 @@ func (p *PaloAlto) SetPanoramaServer(ip string) error {
 // RemoveDevice will remove a device from Panorama. If you specify the optional 'devicegroup' parameter,
 // it will only remove the device from the given device-group.
 func (p *PaloAlto) RemoveDevice(serial string, devicegroup ...string) error {
+	return p.RemoveDeviceContext(context.Background(), serial, devicegroup...)
+}
+
+// RemoveDeviceContext is the same as RemoveDevice, but uses the given context for all API requests.
+func (p *PaloAlto) RemoveDeviceContext(ctx context.Context, serial string, devicegroup ...string) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 
 	if p.DeviceType == "panos" || p.DeviceType != "panorama" {
 		return errors.New("you must be connected to Panorama when removing devices")
 	}
 
 	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		xpath = fmt.Sprintf("/config/mgt-config/devices/entry[@name='%s']", serial)
+		xpath = fmt.Sprintf("/config/mgt-config/devices/entry[@name=%s]", xpathLiteral(serial))
 	}
 
 	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/devices/entry[@name='%s']", devicegroup[0], serial)
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name=%s]/devices/entry[@name=%s]", xpathLiteral(devicegroup[0]), xpathLiteral(serial))
 	}
 
 	query := map[string]string{
@@ -552,7 +679,7 @@ func (p *PaloAlto) RemoveDevice(serial string, devicegroup ...string) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -562,20 +689,25 @@ func (p *PaloAlto) RemoveDevice(serial string, devicegroup ...string) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
-// Tags returns information about all tags on the system.
-func (p *PaloAlto) Tags() (*Tags, error) {
+// Tags returns information about all tags on the system. You can (optionally) specify a Location as the last
+// parameter, such as a device-group on a Panorama device, or a vsys on a firewall. If no location is specified, then
+// all tags are returned from Panorama, and the tags in the session's vsys are returned from a firewall.
+func (p *PaloAlto) Tags(loc ...Location) (*Tags, error) {
+	return p.TagsContext(context.Background(), loc...)
+}
+
+// TagsContext is the same as Tags, but uses the given context for all API requests.
+func (p *PaloAlto) TagsContext(ctx context.Context, loc ...Location) (*Tags, error) {
 	var parsedTags xmlTags
 	var tags Tags
-	var tcolor string
 	xpath := "/config/devices/entry//tag"
 	// xpath := "/config/devices/entry/vsys/entry/tag"
-	r := rested.NewRequest()
 
 	if p.DeviceType == "panos" && p.Panorama == true {
 		xpath = "/config/panorama//tag"
@@ -586,23 +718,36 @@ func (p *PaloAlto) Tags() (*Tags, error) {
 		xpath = "/config/devices/entry//tag"
 	}
 
+	if (p.DeviceType == "panos" && p.Panorama == false) || len(loc) > 0 {
+		base, err := p.locationXpath(loc)
+		if err != nil {
+			return nil, err
+		}
+
+		xpath = base + "/tag"
+	}
+
 	query := map[string]string{
 		"type":   "config",
 		"action": "get",
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	tData := r.Send("get", p.URI, nil, headers, query)
+	tData := p.send(ctx, "get", query)
+	if tData.Error != nil {
+		return nil, tData.Error
+	}
 
 	if err := xml.Unmarshal(tData.Body, &parsedTags); err != nil {
 		return nil, err
 	}
 
 	if parsedTags.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", parsedTags.Code, errorCodes[parsedTags.Code])
+		return nil, newAPIError(tData, query)
 	}
 
 	for _, t := range parsedTags.Tags {
+		var tcolor string
 		tname := t.Name
 		for k, v := range tagColors {
 			if t.Color == v {
@@ -621,30 +766,34 @@ func (p *PaloAlto) Tags() (*Tags, error) {
 
 // CreateTag will add a new tag to the device. You can use the following colors: Red, Green, Blue, Yellow, Copper,
 // Orange, Purple, Gray, Light Green, Cyan, Light Gray, Blue Gray, Lime, Black, Gold, Brown. If creating a tag on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) CreateTag(name, color, comments string, devicegroup ...string) error {
+// Panorama device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys
+// firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) CreateTag(name, color, comments string, loc ...Location) error {
+	return p.CreateTagContext(context.Background(), name, color, comments, loc...)
+}
+
+// CreateTagContext is the same as CreateTag, but uses the given context for all API requests.
+func (p *PaloAlto) CreateTagContext(ctx context.Context, name, color, comments string, loc ...Location) error {
 	var xmlBody string
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
+
+	if err := validateTagName(name); err != nil {
+		return err
+	}
 
 	xmlBody = fmt.Sprintf("<color>%s</color>", tagColors[color])
 
 	if comments != "" {
-		xmlBody += fmt.Sprintf("<comments>%s</comments>", comments)
+		xmlBody += fmt.Sprintf("<comments>%s</comments>", escapeXML(comments))
 	}
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/tag/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/tag/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/tag/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":    "config",
@@ -654,7 +803,7 @@ func (p *PaloAlto) CreateTag(name, color, comments string, devicegroup ...string
 		"key":     p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -664,30 +813,30 @@ func (p *PaloAlto) CreateTag(name, color, comments string, devicegroup ...string
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
-// DeleteTag will remove a tag from the device. If deleting a tag on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) DeleteTag(name string, devicegroup ...string) error {
+// DeleteTag will remove a tag from the device. If deleting a tag on a Panorama device, then specify its location
+// (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can specify a VsysLocation
+// instead.
+func (p *PaloAlto) DeleteTag(name string, loc ...Location) error {
+	return p.DeleteTagContext(context.Background(), name, loc...)
+}
+
+// DeleteTagContext is the same as DeleteTag, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteTagContext(ctx context.Context, name string, loc ...Location) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/tag/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/tag/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/tag/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":   "config",
@@ -696,7 +845,7 @@ func (p *PaloAlto) DeleteTag(name string, devicegroup ...string) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -706,507 +855,134 @@ func (p *PaloAlto) DeleteTag(name string, devicegroup ...string) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
-// ApplyTag will apply the given tag to the specified address or service object(s). You can specify multiple tags
-// by separating them with a comma, i.e. "servers, vm". If you have address/service objects with the same
-// name, then the tag(s) will be applied to all that match. When tagging an object on a Panorama device, specify
-// the given device-group name as the last parameter.
-func (p *PaloAlto) ApplyTag(tag, object string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
-	tags := strings.Split(tag, ",")
-	adObj, _ := p.Addresses()
-	agObj, _ := p.AddressGroups()
-	sObj, _ := p.Services()
-	sgObj, _ := p.ServiceGroups()
-
-	xmlBody := "<tag>"
-	for _, t := range tags {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(t))
-	}
-	xmlBody += "</tag>"
-
-	query := map[string]string{
-		"type":    "config",
-		"action":  "edit",
-		"element": xmlBody,
-		"key":     p.Key,
-	}
-
-	for _, a := range adObj.Addresses {
-		if object == a.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']/tag", object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']/tag", devicegroup[0], object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
-	}
-
-	for _, ag := range agObj.Groups {
-		if object == ag.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/tag", object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/tag", devicegroup[0], object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
-	}
-
-	for _, s := range sObj.Services {
-		if object == s.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']/tag", object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']/tag", devicegroup[0], object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
-	}
-
-	for _, sg := range sgObj.Groups {
-		if object == sg.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/tag", object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/tag", devicegroup[0], object)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
+// ApplyTag will apply the given tag to the specified address or service object. You can specify multiple tags by
+// separating them with a comma, i.e. "servers, vm", and they are added to any tags the object already has. The
+// object's type is found by looking for an address, address group, service and service group with the given name,
+// in that order - use ApplyTagByType to tag other types of objects, or to skip the lookups. When tagging an object on
+// a Panorama device, specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys
+// firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) ApplyTag(tag, object string, loc ...Location) error {
+	return p.ApplyTagContext(context.Background(), tag, object, loc...)
+}
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
+// ApplyTagContext is the same as ApplyTag, but uses the given context for all API requests.
+func (p *PaloAlto) ApplyTagContext(ctx context.Context, tag, object string, loc ...Location) error {
+	_, xpath, err := p.findObject(ctx, object, []string{"address", "address-group", "service", "service-group"}, loc)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return p.applyTag(ctx, xpath, tag)
 }
 
-// RemoveTag will remove a single tag from an address/service object. If deleting a tag from an object on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) RemoveTag(tag, object string, devicegroup ...string) error {
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
-	adObj, _ := p.Addresses()
-	agObj, _ := p.AddressGroups()
-	sObj, _ := p.Services()
-	sgObj, _ := p.ServiceGroups()
+// ApplyTagByType is the same as ApplyTag, but tags the object of the given type. objecttype should be one of:
+// address, address-group, service, service-group, url-category, security-rule, nat-rule or zone. On a Panorama
+// device, rules are looked for in the pre-rulebase, and then the post-rulebase, of the device-group. Zones are
+// configured in a vsys, so on a Panorama device, specify a TemplateLocation or TemplateStackLocation for them.
+func (p *PaloAlto) ApplyTagByType(objecttype, tag, object string, loc ...Location) error {
+	return p.ApplyTagByTypeContext(context.Background(), objecttype, tag, object, loc...)
+}
 
-	query := map[string]string{
-		"type":   "config",
-		"action": "delete",
-		"key":    p.Key,
+// ApplyTagByTypeContext is the same as ApplyTagByType, but uses the given context for all API requests.
+func (p *PaloAlto) ApplyTagByTypeContext(ctx context.Context, objecttype, tag, object string, loc ...Location) error {
+	xpath, err := p.objectXpath(ctx, objecttype, object, loc)
+	if err != nil {
+		return err
 	}
 
-	for _, a := range adObj.Addresses {
-		if object == a.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	return p.applyTag(ctx, xpath, tag)
+}
 
-				return nil
-			}
+// RemoveTag will remove a single tag from an address/service object. The object's type is found the same way as
+// ApplyTag - use RemoveTagByType to remove a tag from other types of objects. If deleting a tag from an object on a
+// Panorama device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys
+// firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) RemoveTag(tag, object string, loc ...Location) error {
+	return p.RemoveTagContext(context.Background(), tag, object, loc...)
+}
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
+// RemoveTagContext is the same as RemoveTag, but uses the given context for all API requests.
+func (p *PaloAlto) RemoveTagContext(ctx context.Context, tag, object string, loc ...Location) error {
+	_, xpath, err := p.findObject(ctx, object, []string{"address", "address-group", "service", "service-group"}, loc)
+	if err != nil {
+		return err
 	}
 
-	for _, ag := range agObj.Groups {
-		if object == ag.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/address-group/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/address-group/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	return p.ConfigDeleteContext(ctx, fmt.Sprintf("%s/tag/member[text()=%s]", xpath, xpathLiteral(tag)))
+}
 
-				return nil
-			}
+// RemoveTagByType is the same as RemoveTag, but removes the tag from the object of the given type. objecttype
+// should be one of the types accepted by ApplyTagByType.
+func (p *PaloAlto) RemoveTagByType(objecttype, tag, object string, loc ...Location) error {
+	return p.RemoveTagByTypeContext(context.Background(), objecttype, tag, object, loc...)
+}
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
+// RemoveTagByTypeContext is the same as RemoveTagByType, but uses the given context for all API requests.
+func (p *PaloAlto) RemoveTagByTypeContext(ctx context.Context, objecttype, tag, object string, loc ...Location) error {
+	xpath, err := p.objectXpath(ctx, objecttype, object, loc)
+	if err != nil {
+		return err
 	}
 
-	for _, s := range sObj.Services {
-		if object == s.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
+	return p.ConfigDeleteContext(ctx, fmt.Sprintf("%s/tag/member[text()=%s]", xpath, xpathLiteral(tag)))
+}
 
-				return nil
-			}
+// applyTag adds the given tag(s), separated by a comma, to the object at xpath.
+func (p *PaloAlto) applyTag(ctx context.Context, xpath, tag string) error {
+	var xmlBody string
 
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
+	for _, t := range strings.Split(tag, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			xmlBody += fmt.Sprintf("<member>%s</member>", escapeXML(t))
 		}
 	}
 
-	for _, sg := range sgObj.Groups {
-		if object == sg.Name {
-			if p.DeviceType == "panos" {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']/tag/member[text()='%s']", object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-				xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']/tag/member[text()='%s']", devicegroup[0], object, tag)
-
-				query["xpath"] = xpath
-
-				resp := r.Send("post", p.URI, nil, nil, query)
-				if resp.Error != nil {
-					return resp.Error
-				}
-
-				if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-					return err
-				}
-
-				if reqError.Status != "success" {
-					return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-				}
-
-				return nil
-			}
-
-			if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-				return errors.New("you must specify a device-group when connected to a Panorama device")
-			}
-		}
+	if xmlBody == "" {
+		return errors.New("you must specify a tag")
 	}
 
-	return nil
+	return p.ConfigSetContext(ctx, xpath+"/tag", xmlBody)
 }
 
-// Commit issues a commit on the device. When issuing a commit against a Panorama device,
-// the configuration will only be committed to Panorama, and not an individual device-group.
-func (p *PaloAlto) Commit() error {
-	var reqError requestError
-	r := rested.NewRequest()
-
-	query := map[string]string{
-		"type": "commit",
-		"cmd":  "<commit></commit>",
-		"key":  p.Key,
-	}
-
-	resp := r.Send("get", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
+// Commit issues a commit on the device, and returns the ID of the commit job. When issuing a commit against a Panorama device,
+// the configuration will only be committed to Panorama, and not an individual device-group. If there are no changes to
+// commit, then the job ID will be empty. Use WaitForJob to find out if the commit succeeded.
+func (p *PaloAlto) Commit() (string, error) {
+	return p.CommitContext(context.Background())
+}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
+// CommitContext is the same as Commit, but uses the given context for all API requests.
+func (p *PaloAlto) CommitContext(ctx context.Context) (string, error) {
+	return p.CommitWithOptionsContext(ctx, nil)
+}
 
-	return nil
+// CommitAll issues a commit to a Panorama device, with the given 'devicegroup,' and returns the ID of the commit job. You can
+// (optionally) specify individual devices within that device group by adding each serial number as an additional parameter.
+// Use WaitForJob to find out if the commit succeeded on each device.
+func (p *PaloAlto) CommitAll(devicegroup string, devices ...string) (string, error) {
+	return p.CommitAllContext(context.Background(), devicegroup, devices...)
 }
 
-// CommitAll issues a commit to a Panorama device, with the given 'devicegroup.' You can (optionally) specify
-// individual devices within that device group by adding each serial number as an additional parameter.
-func (p *PaloAlto) CommitAll(devicegroup string, devices ...string) error {
-	var reqError requestError
+// CommitAllContext is the same as CommitAll, but uses the given context for all API requests.
+func (p *PaloAlto) CommitAllContext(ctx context.Context, devicegroup string, devices ...string) (string, error) {
+	var commit commitResponse
 	var cmd string
 
-	r := rested.NewRequest()
-
 	if p.DeviceType == "panorama" && len(devices) <= 0 {
-		cmd = fmt.Sprintf("<commit-all><shared-policy><device-group><entry name=\"%s\"/></device-group></shared-policy></commit-all>", devicegroup)
+		cmd = fmt.Sprintf("<commit-all><shared-policy><device-group><entry name=\"%s\"/></device-group></shared-policy></commit-all>", escapeXML(devicegroup))
 	}
 
 	if p.DeviceType == "panorama" && len(devices) > 0 {
-		cmd = fmt.Sprintf("<commit-all><shared-policy><device-group><name>%s</name><devices>", devicegroup)
+		cmd = fmt.Sprintf("<commit-all><shared-policy><device-group><name>%s</name><devices>", escapeXML(devicegroup))
 
 		for _, d := range devices {
-			cmd += fmt.Sprintf("<entry name=\"%s\"/>", d)
+			cmd += fmt.Sprintf("<entry name=\"%s\"/>", escapeXML(d))
 		}
 
 		cmd += "</devices></device-group></shared-policy></commit-all>"
@@ -1219,18 +995,18 @@ func (p *PaloAlto) CommitAll(devicegroup string, devices ...string) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
-		return resp.Error
+		return "", resp.Error
 	}
 
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
+	if err := xml.Unmarshal(resp.Body, &commit); err != nil {
+		return "", err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+	if commit.Status != "success" {
+		return "", newAPIError(resp, query)
 	}
 
-	return nil
+	return commit.Job, nil
 }
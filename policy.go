@@ -0,0 +1,455 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// SecurityRules contains a slice of all security rules.
+type SecurityRules struct {
+	Rules []SecurityRule
+}
+
+// SecurityRule contains information about each individual security rule. Fields such as NegateSource,
+// Disabled, LogStart and LogEnd take the values "yes" or "no".
+type SecurityRule struct {
+	Name                    string
+	RuleType                string
+	Description             string
+	Tags                    []string
+	From                    []string
+	To                      []string
+	Source                  []string
+	NegateSource            string
+	SourceUsers             []string
+	HIPProfiles             []string
+	Destination             []string
+	NegateDestination       string
+	Application             []string
+	Service                 []string
+	Category                []string
+	Action                  string
+	LogSetting              string
+	LogStart                string
+	LogEnd                  string
+	Disabled                string
+	Schedule                string
+	ProfileGroup            string
+	AntivirusProfile        string
+	AntiSpywareProfile      string
+	VulnerabilityProfile    string
+	URLFilteringProfile     string
+	FileBlockingProfile     string
+	WildfireAnalysisProfile string
+	DataFilteringProfile    string
+}
+
+// xmlSecurityRules is used for parsing all security rules.
+type xmlSecurityRules struct {
+	XMLName xml.Name          `xml:"response"`
+	Status  string            `xml:"status,attr"`
+	Code    string            `xml:"code,attr"`
+	Rules   []xmlSecurityRule `xml:"result>rules>entry"`
+}
+
+// xmlSecurityRule is used for parsing and creating each individual security rule.
+type xmlSecurityRule struct {
+	XMLName           xml.Name           `xml:"entry"`
+	Name              string             `xml:"name,attr"`
+	RuleType          string             `xml:"rule-type,omitempty"`
+	Description       string             `xml:"description,omitempty"`
+	Tags              *xmlMembers        `xml:"tag,omitempty"`
+	From              *xmlMembers        `xml:"from,omitempty"`
+	To                *xmlMembers        `xml:"to,omitempty"`
+	Source            *xmlMembers        `xml:"source,omitempty"`
+	NegateSource      string             `xml:"negate-source,omitempty"`
+	SourceUsers       *xmlMembers        `xml:"source-user,omitempty"`
+	HIPProfiles       *xmlMembers        `xml:"hip-profiles,omitempty"`
+	Destination       *xmlMembers        `xml:"destination,omitempty"`
+	NegateDestination string             `xml:"negate-destination,omitempty"`
+	Application       *xmlMembers        `xml:"application,omitempty"`
+	Service           *xmlMembers        `xml:"service,omitempty"`
+	Category          *xmlMembers        `xml:"category,omitempty"`
+	Action            string             `xml:"action,omitempty"`
+	LogSetting        string             `xml:"log-setting,omitempty"`
+	LogStart          string             `xml:"log-start,omitempty"`
+	LogEnd            string             `xml:"log-end,omitempty"`
+	Disabled          string             `xml:"disabled,omitempty"`
+	Schedule          string             `xml:"schedule,omitempty"`
+	ProfileSetting    *xmlProfileSetting `xml:"profile-setting,omitempty"`
+	Other             []xmlElement       `xml:",any"`
+}
+
+// xmlProfileSetting is used for parsing and creating the security profiles attached to a rule.
+type xmlProfileSetting struct {
+	Group    *xmlMembers  `xml:"group,omitempty"`
+	Profiles *xmlProfiles `xml:"profiles,omitempty"`
+}
+
+// xmlProfiles is used for parsing and creating the individual security profiles attached to a rule.
+type xmlProfiles struct {
+	Antivirus        *xmlMembers `xml:"virus,omitempty"`
+	AntiSpyware      *xmlMembers `xml:"spyware,omitempty"`
+	Vulnerability    *xmlMembers `xml:"vulnerability,omitempty"`
+	URLFiltering     *xmlMembers `xml:"url-filtering,omitempty"`
+	FileBlocking     *xmlMembers `xml:"file-blocking,omitempty"`
+	WildfireAnalysis *xmlMembers `xml:"wildfire-analysis,omitempty"`
+	DataFiltering    *xmlMembers `xml:"data-filtering,omitempty"`
+}
+
+// xmlElement is used for keeping any part of an entry that isn't mapped to a field, such as the device targeting of
+// a rule on Panorama, so that it can be sent back unchanged when the entry is edited.
+type xmlElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   []byte     `xml:",innerxml"`
+}
+
+// xmlMembers is used for parsing and creating a list of <member> elements. It is always used as a pointer, so that
+// an empty list is left out of the element entirely.
+type xmlMembers struct {
+	Members []string `xml:"member"`
+}
+
+// newMembers returns the given members as an *xmlMembers, or nil if there are none.
+func newMembers(members ...string) *xmlMembers {
+	var m []string
+
+	for _, member := range members {
+		if member != "" {
+			m = append(m, member)
+		}
+	}
+
+	if len(m) <= 0 {
+		return nil
+	}
+
+	return &xmlMembers{Members: m}
+}
+
+// list returns the members, and is safe to call on a nil *xmlMembers.
+func (m *xmlMembers) list() []string {
+	if m == nil {
+		return nil
+	}
+
+	return m.Members
+}
+
+// first returns the first member, or an empty string if there are none.
+func (m *xmlMembers) first() string {
+	if m == nil || len(m.Members) <= 0 {
+		return ""
+	}
+
+	return m.Members[0]
+}
+
+// toXML converts the rule into the format used when sending it to the device.
+func (r *SecurityRule) toXML() *xmlSecurityRule {
+	x := &xmlSecurityRule{
+		Name:              r.Name,
+		RuleType:          r.RuleType,
+		Description:       r.Description,
+		Tags:              newMembers(r.Tags...),
+		From:              newMembers(r.From...),
+		To:                newMembers(r.To...),
+		Source:            newMembers(r.Source...),
+		NegateSource:      r.NegateSource,
+		SourceUsers:       newMembers(r.SourceUsers...),
+		HIPProfiles:       newMembers(r.HIPProfiles...),
+		Destination:       newMembers(r.Destination...),
+		NegateDestination: r.NegateDestination,
+		Application:       newMembers(r.Application...),
+		Service:           newMembers(r.Service...),
+		Category:          newMembers(r.Category...),
+		Action:            r.Action,
+		LogSetting:        r.LogSetting,
+		LogStart:          r.LogStart,
+		LogEnd:            r.LogEnd,
+		Disabled:          r.Disabled,
+		Schedule:          r.Schedule,
+	}
+
+	profiles := &xmlProfiles{
+		Antivirus:        newMembers(r.AntivirusProfile),
+		AntiSpyware:      newMembers(r.AntiSpywareProfile),
+		Vulnerability:    newMembers(r.VulnerabilityProfile),
+		URLFiltering:     newMembers(r.URLFilteringProfile),
+		FileBlocking:     newMembers(r.FileBlockingProfile),
+		WildfireAnalysis: newMembers(r.WildfireAnalysisProfile),
+		DataFiltering:    newMembers(r.DataFilteringProfile),
+	}
+
+	if r.ProfileGroup != "" {
+		x.ProfileSetting = &xmlProfileSetting{Group: newMembers(r.ProfileGroup)}
+	} else if *profiles != (xmlProfiles{}) {
+		x.ProfileSetting = &xmlProfileSetting{Profiles: profiles}
+	}
+
+	return x
+}
+
+// rule converts the parsed rule into a SecurityRule.
+func (x *xmlSecurityRule) rule() SecurityRule {
+	r := SecurityRule{
+		Name:              x.Name,
+		RuleType:          x.RuleType,
+		Description:       x.Description,
+		Tags:              x.Tags.list(),
+		From:              x.From.list(),
+		To:                x.To.list(),
+		Source:            x.Source.list(),
+		NegateSource:      x.NegateSource,
+		SourceUsers:       x.SourceUsers.list(),
+		HIPProfiles:       x.HIPProfiles.list(),
+		Destination:       x.Destination.list(),
+		NegateDestination: x.NegateDestination,
+		Application:       x.Application.list(),
+		Service:           x.Service.list(),
+		Category:          x.Category.list(),
+		Action:            x.Action,
+		LogSetting:        x.LogSetting,
+		LogStart:          x.LogStart,
+		LogEnd:            x.LogEnd,
+		Disabled:          x.Disabled,
+		Schedule:          x.Schedule,
+	}
+
+	if ps := x.ProfileSetting; ps != nil {
+		r.ProfileGroup = ps.Group.first()
+
+		if pr := ps.Profiles; pr != nil {
+			r.AntivirusProfile = pr.Antivirus.first()
+			r.AntiSpywareProfile = pr.AntiSpyware.first()
+			r.VulnerabilityProfile = pr.Vulnerability.first()
+			r.URLFilteringProfile = pr.URLFiltering.first()
+			r.FileBlockingProfile = pr.FileBlocking.first()
+			r.WildfireAnalysisProfile = pr.WildfireAnalysis.first()
+			r.DataFilteringProfile = pr.DataFiltering.first()
+		}
+	}
+
+	return r
+}
+
+// rulebaseXpath returns the xpath to the rules of the given rule type (i.e. "security" or "nat"). On a Panorama
+// device, rulebase must be one of: pre or post, and a device-group (or shared) location must be specified.
+func (p *PaloAlto) rulebaseXpath(ruletype, rulebase string, loc []Location) (string, error) {
+	l, err := p.location(loc)
+	if err != nil {
+		return "", err
+	}
+
+	base, err := l.Xpath()
+	if err != nil {
+		return "", err
+	}
+
+	if p.DeviceType == "panos" {
+		if l.Shared {
+			return "", errors.New("rules can not be shared on a firewall, you must specify a vsys")
+		}
+
+		return fmt.Sprintf("%s/rulebase/%s/rules", base, ruletype), nil
+	}
+
+	if l.DeviceGroup == "" && !l.Shared {
+		return "", errors.New("you must specify a device-group (or shared) location for rules when connected to a Panorama device")
+	}
+
+	if rulebase != "pre" && rulebase != "post" {
+		return "", errors.New("rulebase must be one of: pre or post when connected to a Panorama device")
+	}
+
+	return fmt.Sprintf("%s/%s-rulebase/%s/rules", base, rulebase, ruletype), nil
+}
+
+// setEntry creates (action "set") or replaces (action "edit") a single entry, such as a rule or object, in the
+// given xpath.
+func (p *PaloAlto) setEntry(ctx context.Context, action, xpath, name string, entry interface{}) error {
+	if action == "edit" {
+		return p.ConfigEditContext(ctx, fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(name)), entry)
+	}
+
+	return p.ConfigSetContext(ctx, xpath, entry)
+}
+
+// getEntry retrieves a single entry, such as a rule or object, from the candidate configuration and parses it into v.
+// If the entry doesn't exist, then v is left as-is.
+func (p *PaloAlto) getEntry(ctx context.Context, xpath, name string, v interface{}) error {
+	data, err := p.ConfigGetContext(ctx, fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(name)))
+	if IsNotFound(err) || (err == nil && len(data) <= 0) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return xml.Unmarshal(data, v)
+}
+
+// deleteRule removes the given rule from the rules xpath.
+func (p *PaloAlto) deleteRule(ctx context.Context, xpath, name string) error {
+	return p.ConfigDeleteContext(ctx, fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(name)))
+}
+
+// moveRule moves the given rule within the rules xpath. Where must be one of: top, bottom, before or after, and
+// dest is the rule to move before or after.
+func (p *PaloAlto) moveRule(ctx context.Context, xpath, name, where, dest string) error {
+	return p.ConfigMoveContext(ctx, fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(name)), where, dest)
+}
+
+// SecurityRules returns information about all of the security rules. When ran against a Panorama device, rulebase
+// must be one of: pre or post, and you must specify its location (i.e. a DeviceGroupLocation) as the last parameter.
+// On a firewall, the rulebase is ignored, and you can (optionally) specify a VsysLocation as the last parameter.
+func (p *PaloAlto) SecurityRules(rulebase string, loc ...Location) (*SecurityRules, error) {
+	return p.SecurityRulesContext(context.Background(), rulebase, loc...)
+}
+
+// SecurityRulesContext is the same as SecurityRules, but uses the given context for all API requests.
+func (p *PaloAlto) SecurityRulesContext(ctx context.Context, rulebase string, loc ...Location) (*SecurityRules, error) {
+	var parsedRules xmlSecurityRules
+	var rules SecurityRules
+
+	xpath, err := p.rulebaseXpath("security", rulebase, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	query := map[string]string{
+		"type":   "config",
+		"action": "get",
+		"xpath":  xpath,
+		"key":    p.Key,
+	}
+
+	ruleData := p.send(ctx, "get", query)
+	if ruleData.Error != nil {
+		return nil, ruleData.Error
+	}
+
+	if err := xml.Unmarshal(ruleData.Body, &parsedRules); err != nil {
+		return nil, err
+	}
+
+	if parsedRules.Status != "success" {
+		return nil, newAPIError(ruleData, query)
+	}
+
+	for _, r := range parsedRules.Rules {
+		rules.Rules = append(rules.Rules, r.rule())
+	}
+
+	return &rules, nil
+}
+
+// CreateSecurityRule adds a new security rule to the bottom of the rulebase. Any zones, addresses, applications or
+// services that are not specified default to "any." When creating a rule on a Panorama device, rulebase must be one
+// of: pre or post, and you must specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a
+// multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) CreateSecurityRule(rule *SecurityRule, rulebase string, loc ...Location) error {
+	return p.CreateSecurityRuleContext(context.Background(), rule, rulebase, loc...)
+}
+
+// CreateSecurityRuleContext is the same as CreateSecurityRule, but uses the given context for all API requests.
+func (p *PaloAlto) CreateSecurityRuleContext(ctx context.Context, rule *SecurityRule, rulebase string, loc ...Location) error {
+	if rule == nil || rule.Name == "" {
+		return errors.New("you must specify a name for the security rule")
+	}
+
+	if err := validateName(rule.Name); err != nil {
+		return err
+	}
+
+	if rule.Action == "" {
+		return errors.New("you must specify an action for the security rule")
+	}
+
+	xpath, err := p.rulebaseXpath("security", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	r := *rule
+	for _, members := range []*[]string{&r.From, &r.To, &r.Source, &r.Destination, &r.Application, &r.Service} {
+		if len(*members) <= 0 {
+			*members = []string{"any"}
+		}
+	}
+
+	return p.setEntry(ctx, "set", xpath, r.Name, r.toXML())
+}
+
+// EditSecurityRule replaces the existing security rule of the same name with the given rule. Every field of the rule
+// is overwritten, so you should start from a rule returned by SecurityRules. Any settings that SecurityRule doesn't
+// have a field for, such as target devices, source and destination HIP profiles, QoS marking or the group tag, are
+// read from the existing rule and kept as they are. When editing a rule on a Panorama device, rulebase must be one
+// of: pre or post, and you must specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a
+// multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) EditSecurityRule(rule *SecurityRule, rulebase string, loc ...Location) error {
+	return p.EditSecurityRuleContext(context.Background(), rule, rulebase, loc...)
+}
+
+// EditSecurityRuleContext is the same as EditSecurityRule, but uses the given context for all API requests.
+func (p *PaloAlto) EditSecurityRuleContext(ctx context.Context, rule *SecurityRule, rulebase string, loc ...Location) error {
+	if rule == nil || rule.Name == "" {
+		return errors.New("you must specify a name for the security rule")
+	}
+
+	var existing xmlSecurityRule
+
+	xpath, err := p.rulebaseXpath("security", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	if err := p.getEntry(ctx, xpath, rule.Name, &existing); err != nil {
+		return err
+	}
+
+	x := rule.toXML()
+	x.Other = existing.Other
+
+	return p.setEntry(ctx, "edit", xpath, rule.Name, x)
+}
+
+// DeleteSecurityRule removes the given security rule. When deleting a rule on a Panorama device, rulebase must be
+// one of: pre or post, and you must specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a
+// multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) DeleteSecurityRule(name, rulebase string, loc ...Location) error {
+	return p.DeleteSecurityRuleContext(context.Background(), name, rulebase, loc...)
+}
+
+// DeleteSecurityRuleContext is the same as DeleteSecurityRule, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteSecurityRuleContext(ctx context.Context, name, rulebase string, loc ...Location) error {
+	xpath, err := p.rulebaseXpath("security", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	return p.deleteRule(ctx, xpath, name)
+}
+
+// MoveSecurityRule moves the given security rule within the rulebase. Where must be one of: top, bottom, before or
+// after. When moving a rule before or after another, specify that rule's name as dest, otherwise use an empty
+// string. When moving a rule on a Panorama device, rulebase must be one of: pre or post, and you must specify its
+// location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can specify a
+// VsysLocation instead.
+func (p *PaloAlto) MoveSecurityRule(name, where, dest, rulebase string, loc ...Location) error {
+	return p.MoveSecurityRuleContext(context.Background(), name, where, dest, rulebase, loc...)
+}
+
+// MoveSecurityRuleContext is the same as MoveSecurityRule, but uses the given context for all API requests.
+func (p *PaloAlto) MoveSecurityRuleContext(ctx context.Context, name, where, dest, rulebase string, loc ...Location) error {
+	xpath, err := p.rulebaseXpath("security", rulebase, loc)
+	if err != nil {
+		return err
+	}
+
+	return p.moveRule(ctx, xpath, name, where, dest)
+}
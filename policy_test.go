@@ -0,0 +1,143 @@
+package panos
+
+import (
+	"encoding/xml"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// existingSecurityRule is a rule as returned by the device, including settings SecurityRule has no fields for.
+const existingSecurityRule = `<entry name="web"><from><member>trust</member></from><to><member>untrust</member></to>` +
+	`<source><member>any</member></source><destination><member>web-server</member></destination>` +
+	`<application><member>web-browsing</member></application><service><member>application-default</member></service>` +
+	`<action>allow</action><profile-setting><group><member>default</member></group></profile-setting>` +
+	`<target><devices><entry name="0123456789"/></devices><negate>no</negate></target>` +
+	`<source-hip><member>any</member></source-hip><group-tag>web</group-tag></entry>`
+
+func TestSecurityRuleToXML(t *testing.T) {
+	r := &SecurityRule{
+		Name:             "web",
+		Tags:             []string{"prod"},
+		Source:           []string{"10.1.1.0/24"},
+		Action:           "allow",
+		AntivirusProfile: "default",
+	}
+
+	b, err := xml.Marshal(r.toXML())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<entry name="web"><tag><member>prod</member></tag><source><member>10.1.1.0/24</member></source>` +
+		`<action>allow</action><profile-setting><profiles><virus><member>default</member></virus></profiles></profile-setting></entry>`
+
+	if string(b) != want {
+		t.Errorf("got %s", b)
+	}
+
+	r.ProfileGroup = "strict"
+
+	if ps := r.toXML().ProfileSetting; ps.Profiles != nil || ps.Group.first() != "strict" {
+		t.Error("a profile group should replace the individual profiles")
+	}
+}
+
+func TestSecurityRulesParse(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result><rules>` + existingSecurityRule + `</rules></result></response>`
+	})
+
+	rules, err := p.SecurityRules("", VsysLocation("vsys2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := SecurityRule{
+		Name:         "web",
+		From:         []string{"trust"},
+		To:           []string{"untrust"},
+		Source:       []string{"any"},
+		Destination:  []string{"web-server"},
+		Application:  []string{"web-browsing"},
+		Service:      []string{"application-default"},
+		Action:       "allow",
+		ProfileGroup: "default",
+	}
+
+	if len(rules.Rules) != 1 || !reflect.DeepEqual(rules.Rules[0], want) {
+		t.Errorf("got %+v", rules.Rules)
+	}
+
+	if xpath := d.sent("get")[0].Get("xpath"); !strings.HasSuffix(xpath, "/vsys/entry[@name='vsys2']/rulebase/security/rules") {
+		t.Errorf("unexpected xpath %s", xpath)
+	}
+}
+
+func TestCreateSecurityRuleDefaults(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	if err := p.CreateSecurityRule(&SecurityRule{Name: "allow-dns", Service: []string{"dns"}, Action: "allow"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	element := d.sent("set")[0].Get("element")
+	for _, want := range []string{"<from><member>any</member></from>", "<application><member>any</member></application>", "<service><member>dns</member></service>"} {
+		if !strings.Contains(element, want) {
+			t.Errorf("%s is missing %s", element, want)
+		}
+	}
+
+	if err := p.CreateSecurityRule(&SecurityRule{Name: "bad'name", Action: "allow"}, ""); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+
+	p.DeviceType = "panorama"
+	if err := p.CreateSecurityRule(&SecurityRule{Name: "r", Action: "allow"}, "middle", DeviceGroupLocation("dg")); err == nil {
+		t.Error("expected an error for an invalid rulebase")
+	}
+}
+
+func TestEditSecurityRuleKeepsUnmappedSettings(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") == "get" {
+			return `<response status="success"><result total-count="1" count="1">` + existingSecurityRule + `</result></response>`
+		}
+
+		return success
+	})
+
+	p.DeviceType = "panorama"
+
+	rule := &SecurityRule{Name: "web", Description: "updated", Action: "deny"}
+	if err := p.EditSecurityRule(rule, "pre", DeviceGroupLocation("branch")); err != nil {
+		t.Fatal(err)
+	}
+
+	edits := d.sent("edit")
+	if len(edits) != 1 {
+		t.Fatalf("got %d edit requests", len(edits))
+	}
+
+	if xpath := edits[0].Get("xpath"); !strings.HasSuffix(xpath, "/pre-rulebase/security/rules/entry[@name='web']") {
+		t.Errorf("unexpected xpath %s", xpath)
+	}
+
+	element := edits[0].Get("element")
+	for _, want := range []string{
+		"<description>updated</description>",
+		"<action>deny</action>",
+		`<target><devices><entry name="0123456789"/></devices><negate>no</negate></target>`,
+		"<source-hip><member>any</member></source-hip>",
+		"<group-tag>web</group-tag>",
+	} {
+		if !strings.Contains(element, want) {
+			t.Errorf("%s is missing %s", element, want)
+		}
+	}
+
+	if strings.Contains(element, "web-browsing") {
+		t.Errorf("%s kept a field that was overwritten", element)
+	}
+}
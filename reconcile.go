@@ -0,0 +1,700 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// planOrder is the order in which object types are created and edited, so that tags exist before the objects that
+// use them, and objects exist before the groups that contain them. Deletes are done in the reverse order.
+var planOrder = []string{"tag", "address", "service", "url-category", "address-group", "service-group"}
+
+// planPaths contains the xpath (relative to the location) of each object type that can be planned.
+var planPaths = map[string]string{
+	"tag":           "tag",
+	"address":       "address",
+	"service":       "service",
+	"url-category":  "profiles/custom-url-category",
+	"address-group": "address-group",
+	"service-group": "service-group",
+}
+
+// DesiredState describes all of the address objects, address groups, service objects, service groups, tags and
+// custom URL categories that should exist in a location. Pass it to Plan to find out what needs to change. Only the
+// settings that are set on each object are managed: any that are left empty, such as the timeout overrides of a
+// service, along with any that the types don't have, such as the tags of an address group, are left as they are on
+// the device. Lists that are set, such as the members of a group or the tags of an object, replace the existing ones.
+type DesiredState struct {
+	Addresses     []Address
+	AddressGroups []AddressGroup
+	Services      []Service
+	ServiceGroups []ServiceGroup
+	Tags          []Tag
+	URLCategories []CustomURL
+	// Renames are planned before anything else, so that an existing object can be renamed in place (along with any
+	// references to it in groups and rules) instead of being deleted and created again under its new name.
+	Renames []Rename
+	// Prune deletes any objects in the location that are not in the desired state. Without it, the plan only
+	// creates, edits and renames objects.
+	Prune bool
+}
+
+// Rename describes an object that should be renamed. ObjectType should be one of: address, address-group, service,
+// service-group, tag or url-category.
+type Rename struct {
+	ObjectType string
+	From       string
+	To         string
+}
+
+// Plan contains the changes needed to bring a location in line with a DesiredState, in the order they are applied:
+// renames first, then creates and edits (tags, then objects, then groups), and finally deletes (groups, then
+// objects, then tags).
+type Plan struct {
+	Changes []PlanChange
+
+	loc []Location
+}
+
+// PlanChange describes an individual change in a plan. Action is one of: create, edit, delete or rename, and NewName
+// is only set when renaming.
+type PlanChange struct {
+	Action     string
+	ObjectType string
+	Name       string
+	NewName    string
+
+	element  interface{}
+	replaced []string
+	removed  []string
+}
+
+// planObject contains an object of the desired state or the live configuration. Fields and lists contain each of
+// its settings by their path within the entry, i.e. "protocol/tcp/port" or "static", and choice is the path of the
+// setting that decides what kind of object it is, such as "fqdn" for an address. The element is only set for desired
+// objects, and members only for groups.
+type planObject struct {
+	name    string
+	element interface{}
+	fields  map[string]string
+	lists   map[string][]string
+	choice  string
+	members []string
+}
+
+// xmlPlanEntries is used for parsing the entries of an object type in the live configuration.
+type xmlPlanEntries struct {
+	Entries []xmlPlanEntry `xml:"entry"`
+}
+
+// xmlPlanEntry is used for parsing the name and the raw contents of each entry.
+type xmlPlanEntry struct {
+	Name  string `xml:"name,attr"`
+	Inner []byte `xml:",innerxml"`
+}
+
+// xmlCustomURLEntry is used for creating or editing a custom URL category.
+type xmlCustomURLEntry struct {
+	XMLName     xml.Name    `xml:"entry"`
+	Name        string      `xml:"name,attr"`
+	Description string      `xml:"description,omitempty"`
+	List        *xmlMembers `xml:"list,omitempty"`
+	Type        string      `xml:"type,omitempty"`
+}
+
+// String returns the change in a readable form, i.e. "create address web-server".
+func (c PlanChange) String() string {
+	if c.Action == "rename" {
+		return fmt.Sprintf("rename %s %s to %s", c.ObjectType, c.Name, c.NewName)
+	}
+
+	return fmt.Sprintf("%s %s %s", c.Action, c.ObjectType, c.Name)
+}
+
+// String returns each change of the plan on its own line, or "no changes" if there is nothing to do.
+func (pl *Plan) String() string {
+	if len(pl.Changes) <= 0 {
+		return "no changes"
+	}
+
+	lines := make([]string, len(pl.Changes))
+	for i, c := range pl.Changes {
+		lines[i] = c.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Plan compares the desired state against the objects that are configured in the location, and returns the changes
+// needed to make them match. Only the settings each desired object has are compared, and edits are merged into the
+// existing objects, so that anything the desired state doesn't manage is kept. Nothing is changed on the device until
+// the plan is given to ApplyPlan. When ran against a Panorama device, you must specify a location (i.e. a
+// DeviceGroupLocation) as the last parameter. On a firewall, you can (optionally) specify a VsysLocation instead.
+func (p *PaloAlto) Plan(desired *DesiredState, loc ...Location) (*Plan, error) {
+	return p.PlanContext(context.Background(), desired, loc...)
+}
+
+// PlanContext is the same as Plan, but uses the given context for all API requests.
+func (p *PaloAlto) PlanContext(ctx context.Context, desired *DesiredState, loc ...Location) (*Plan, error) {
+	if desired == nil {
+		return nil, errors.New("you must specify the desired state")
+	}
+
+	if _, err := p.locationXpath(loc); err != nil {
+		return nil, err
+	}
+
+	want, err := p.desiredObjects(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := p.liveObjects(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{loc: loc}
+
+	for _, r := range desired.Renames {
+		objs, ok := live[r.ObjectType]
+		if !ok {
+			return nil, fmt.Errorf("unknown object type %s, should be one of: address, address-group, service, service-group, tag or url-category", r.ObjectType)
+		}
+
+		validate := validateName
+		if r.ObjectType == "tag" {
+			validate = validateTagName
+		}
+
+		if err := validate(r.To); err != nil {
+			return nil, err
+		}
+
+		from, to := indexOf(objs, r.From), indexOf(objs, r.To)
+
+		switch {
+		case from < 0:
+			continue
+		case to >= 0:
+			return nil, fmt.Errorf("can not rename %s %s to %s, because it already exists", r.ObjectType, r.From, r.To)
+		}
+
+		objs[from].name = r.To
+		renameReferences(live, r)
+		plan.Changes = append(plan.Changes, PlanChange{Action: "rename", ObjectType: r.ObjectType, Name: r.From, NewName: r.To})
+	}
+
+	for _, objecttype := range planOrder {
+		objs, err := orderGroups(objecttype, want[objecttype])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range objs {
+			i := indexOf(live[objecttype], o.name)
+			if i < 0 {
+				plan.Changes = append(plan.Changes, PlanChange{Action: "create", ObjectType: objecttype, Name: o.name, element: p.newEntry(o.element)})
+				continue
+			}
+
+			if changed, replaced, removed := planDiff(o, live[objecttype][i]); changed {
+				plan.Changes = append(plan.Changes, PlanChange{
+					Action:     "edit",
+					ObjectType: objecttype,
+					Name:       o.name,
+					element:    o.element,
+					replaced:   replaced,
+					removed:    removed,
+				})
+			}
+		}
+	}
+
+	if !desired.Prune {
+		return plan, nil
+	}
+
+	for i := len(planOrder) - 1; i >= 0; i-- {
+		objecttype := planOrder[i]
+
+		var unwanted []planObject
+		for _, o := range live[objecttype] {
+			if indexOf(want[objecttype], o.name) < 0 {
+				unwanted = append(unwanted, o)
+			}
+		}
+
+		objs, err := orderGroups(objecttype, unwanted)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := len(objs) - 1; j >= 0; j-- {
+			plan.Changes = append(plan.Changes, PlanChange{Action: "delete", ObjectType: objecttype, Name: objs[j].name})
+		}
+	}
+
+	return plan, nil
+}
+
+// ApplyPlan makes each change of the plan on the device, in order, in the same location the plan was made for. Edits
+// are merged into the existing objects, and any members or settings they no longer have are removed. It stops at
+// the first change that fails, and returns an error saying which change it was. The changes before it are not
+// undone.
+func (p *PaloAlto) ApplyPlan(plan *Plan) error {
+	return p.ApplyPlanContext(context.Background(), plan)
+}
+
+// ApplyPlanContext is the same as ApplyPlan, but uses the given context for all API requests.
+func (p *PaloAlto) ApplyPlanContext(ctx context.Context, plan *Plan) error {
+	if plan == nil {
+		return errors.New("you must specify a plan")
+	}
+
+	base, err := p.locationXpath(plan.loc)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range plan.Changes {
+		xpath := fmt.Sprintf("%s/%s", base, planPaths[c.ObjectType])
+		entry := fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(c.Name))
+
+		switch c.Action {
+		case "create":
+			err = p.setEntry(ctx, "set", xpath, c.Name, c.element)
+		case "edit":
+			err = p.applyEdit(ctx, xpath, c)
+		case "delete":
+			err = p.ConfigDeleteContext(ctx, entry)
+		case "rename":
+			err = p.ConfigRenameContext(ctx, entry, c.NewName)
+		default:
+			err = errors.New("action should be one of: create, edit, delete or rename")
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", c, err)
+		}
+	}
+
+	return nil
+}
+
+// applyEdit merges the desired object of the change into the existing one. Settings that are replaced by a different
+// kind, such as an fqdn replacing an ip-netmask, are deleted first, and members that are no longer wanted are deleted
+// afterwards, so that a group is never left empty.
+func (p *PaloAlto) applyEdit(ctx context.Context, xpath string, c PlanChange) error {
+	entry := fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(c.Name))
+
+	for _, node := range c.replaced {
+		if err := p.ConfigDeleteContext(ctx, entry+"/"+node); err != nil {
+			return err
+		}
+	}
+
+	if err := p.setEntry(ctx, "set", xpath, c.Name, c.element); err != nil {
+		return err
+	}
+
+	for _, node := range c.removed {
+		if err := p.ConfigDeleteContext(ctx, entry+"/"+node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// desiredObjects converts each object of the desired state into the form used when creating or editing it, grouped
+// by object type, and makes sure that each name is valid and only used once.
+func (p *PaloAlto) desiredObjects(desired *DesiredState) (map[string][]planObject, error) {
+	want := map[string][]planObject{}
+
+	add := func(objecttype, name string, element interface{}) error {
+		var entry xmlPlanEntry
+
+		validate := validateName
+		if objecttype == "tag" {
+			validate = validateTagName
+		}
+
+		if err := validate(name); err != nil {
+			return err
+		}
+
+		if indexOf(want[objecttype], name) >= 0 {
+			return fmt.Errorf("%s %s is specified more than once", objecttype, name)
+		}
+
+		data, err := xml.Marshal(element)
+		if err != nil {
+			return err
+		}
+
+		if err := xml.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+
+		o, err := newPlanObject(objecttype, name, entry.Inner)
+		if err != nil {
+			return err
+		}
+
+		o.element = element
+		want[objecttype] = append(want[objecttype], o)
+
+		return nil
+	}
+
+	for _, t := range desired.Tags {
+		color, ok := tagColors[t.Color]
+		if t.Color != "" && !ok {
+			return nil, fmt.Errorf("unknown tag color %s", t.Color)
+		}
+
+		if err := add("tag", t.Name, &xmlTagEntry{Name: t.Name, Color: color, Comments: t.Comments}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range desired.Addresses {
+		x, err := a.toXML()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := add("address", a.Name, x); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range desired.Services {
+		x, err := s.toXML()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := add("service", s.Name, x); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, u := range desired.URLCategories {
+		if err := add("url-category", u.Name, customURLEntry(u)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, g := range desired.AddressGroups {
+		if len(g.Members) > 0 && g.DynamicFilter != "" {
+			return nil, fmt.Errorf("address group %s can not have both members and a dynamic filter", g.Name)
+		}
+
+		if err := add("address-group", g.Name, addressGroupEntry(g)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, g := range desired.ServiceGroups {
+		if err := add("service-group", g.Name, serviceGroupEntry(g)); err != nil {
+			return nil, err
+		}
+	}
+
+	return want, nil
+}
+
+// liveObjects retrieves the objects that are configured in the location, grouped by object type. Each entry is
+// parsed as-is, so that every setting it has can be compared, including those the object types don't have a field
+// for.
+func (p *PaloAlto) liveObjects(ctx context.Context, loc []Location) (map[string][]planObject, error) {
+	live := map[string][]planObject{}
+
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, objecttype := range planOrder {
+		var entries xmlPlanEntries
+
+		live[objecttype] = []planObject{}
+
+		data, err := p.ConfigGetContext(ctx, fmt.Sprintf("%s/%s", base, planPaths[objecttype]))
+		if err != nil && !IsNotFound(err) {
+			return nil, err
+		}
+
+		if len(data) > 0 {
+			if err := xml.Unmarshal(data, &entries); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, e := range entries.Entries {
+			o, err := newPlanObject(objecttype, e.Name, e.Inner)
+			if err != nil {
+				return nil, err
+			}
+
+			live[objecttype] = append(live[objecttype], o)
+		}
+	}
+
+	return live, nil
+}
+
+// newEntry returns the element used for creating a new object. PAN-OS 9.0 and later require the type of a custom
+// URL category to be set, so new categories default to a URL List. Existing categories are edited without a type,
+// so that they keep theirs, whether it is a URL List or a Category Match.
+func (p *PaloAlto) newEntry(element interface{}) interface{} {
+	if x, ok := element.(*xmlCustomURLEntry); ok && x.Type == "" && splitSWVersion(p.SoftwareVersion)[0] >= 9 {
+		u := *x
+		u.Type = "URL List"
+
+		return &u
+	}
+
+	return element
+}
+
+// customURLEntry converts a custom URL category into the form used when creating or editing it.
+func customURLEntry(u CustomURL) *xmlCustomURLEntry {
+	return &xmlCustomURLEntry{Name: u.Name, Description: u.Description, List: newMembers(u.Members...)}
+}
+
+// addressGroupEntry converts an address group into the form used when creating or editing it.
+func addressGroupEntry(g AddressGroup) *xmlAddressGroupEntry {
+	x := &xmlAddressGroupEntry{Name: g.Name, Static: newMembers(g.Members...), Description: g.Description}
+
+	if g.DynamicFilter != "" {
+		x.Dynamic = &xmlDynamicGroup{Filter: g.DynamicFilter}
+	}
+
+	return x
+}
+
+// serviceGroupEntry converts a service group into the form used when creating or editing it.
+func serviceGroupEntry(g ServiceGroup) *xmlServiceGroupEntry {
+	return &xmlServiceGroupEntry{Name: g.Name, Members: newMembers(g.Members...), Description: g.Description}
+}
+
+// newPlanObject parses the contents of an entry into each of its settings, by their path within the entry. Members
+// are collected into lists, and any other element without children is a field.
+func newPlanObject(objecttype, name string, inner []byte) (planObject, error) {
+	var path []string
+	var parents []bool
+	var text strings.Builder
+
+	o := planObject{name: name, fields: map[string]string{}, lists: map[string][]string{}}
+	d := xml.NewDecoder(bytes.NewReader(inner))
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return o, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(parents) > 0 {
+				parents[len(parents)-1] = true
+			}
+
+			path = append(path, t.Name.Local)
+			parents = append(parents, false)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			leaf := !parents[len(parents)-1]
+			node := strings.Join(path, "/")
+			path, parents = path[:len(path)-1], parents[:len(parents)-1]
+
+			switch {
+			case t.Name.Local == "member":
+				list := strings.Join(path, "/")
+				o.lists[list] = append(o.lists[list], strings.TrimSpace(text.String()))
+			case leaf:
+				o.fields[node] = strings.TrimSpace(text.String())
+			}
+
+			text.Reset()
+		}
+	}
+
+	switch objecttype {
+	case "address":
+		for _, c := range []string{"ip-netmask", "ip-range", "ip-wildcard", "fqdn"} {
+			if _, ok := o.fields[c]; ok {
+				o.choice = c
+			}
+		}
+	case "service":
+		for _, c := range []string{"protocol/tcp", "protocol/udp", "protocol/sctp"} {
+			if _, ok := o.fields[c+"/port"]; ok {
+				o.choice = c
+			}
+		}
+	case "address-group":
+		if _, ok := o.lists["static"]; ok {
+			o.choice = "static"
+		} else if _, ok := o.fields["dynamic/filter"]; ok {
+			o.choice = "dynamic"
+		}
+
+		o.members = o.lists["static"]
+	case "service-group":
+		o.members = o.lists["members"]
+	}
+
+	return o, nil
+}
+
+// planDiff compares the settings of a desired object against the live one, ignoring any setting the desired object
+// doesn't have. It returns whether they differ, the settings that have to be deleted because the object changes
+// kind (i.e. from static to dynamic), and the members that have to be deleted because they are no longer wanted.
+// Members are compared regardless of their order, and disable-override is the same as "no" when it isn't set.
+func planDiff(want, live planObject) (bool, []string, []string) {
+	var replaced, removed []string
+	changed := false
+
+	if want.choice != "" && live.choice != "" && want.choice != live.choice {
+		changed = true
+		replaced = append(replaced, live.choice)
+	}
+
+	for node, v := range want.fields {
+		if live.fields[node] != v && !(node == "disable-override" && v == "no" && live.fields[node] == "") {
+			changed = true
+		}
+	}
+
+	lists := make([]string, 0, len(want.lists))
+	for list := range want.lists {
+		lists = append(lists, list)
+	}
+
+	sort.Strings(lists)
+
+	for _, list := range lists {
+		wanted := map[string]bool{}
+		for _, m := range want.lists[list] {
+			wanted[m] = true
+		}
+
+		existing := map[string]bool{}
+		for _, m := range live.lists[list] {
+			existing[m] = true
+
+			if !wanted[m] {
+				changed = true
+				removed = append(removed, fmt.Sprintf("%s/member[text()=%s]", list, xpathLiteral(m)))
+			}
+		}
+
+		for m := range wanted {
+			if !existing[m] {
+				changed = true
+			}
+		}
+	}
+
+	return changed, replaced, removed
+}
+
+// renameReferences replaces the name of the renamed object in the groups that contain it, or the objects that are
+// tagged with it, the same way as the device does when the rename is applied.
+func renameReferences(live map[string][]planObject, r Rename) {
+	refs := map[string]string{}
+
+	switch r.ObjectType {
+	case "address", "address-group":
+		refs["address-group"] = "static"
+	case "service", "service-group":
+		refs["service-group"] = "members"
+	case "tag":
+		for _, objecttype := range planOrder {
+			if objecttype != "tag" {
+				refs[objecttype] = "tag"
+			}
+		}
+	}
+
+	for objecttype, list := range refs {
+		for _, o := range live[objecttype] {
+			for i, m := range o.lists[list] {
+				if m == r.From {
+					o.lists[list][i] = r.To
+				}
+			}
+		}
+	}
+}
+
+// orderGroups sorts groups of the given object type so that any group that is a member of another group comes
+// before it. Other object types are returned as-is.
+func orderGroups(objecttype string, objs []planObject) ([]planObject, error) {
+	if objecttype != "address-group" && objecttype != "service-group" {
+		return objs, nil
+	}
+
+	var ordered []planObject
+	state := map[string]int{}
+
+	var visit func(o planObject) error
+	visit = func(o planObject) error {
+		switch state[o.name] {
+		case 1:
+			return fmt.Errorf("%s %s contains itself", objecttype, o.name)
+		case 2:
+			return nil
+		}
+
+		state[o.name] = 1
+
+		for _, m := range o.members {
+			if i := indexOf(objs, m); i >= 0 {
+				if err := visit(objs[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[o.name] = 2
+		ordered = append(ordered, o)
+
+		return nil
+	}
+
+	for _, o := range objs {
+		if err := visit(o); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// indexOf returns the position of the object with the given name, or -1 if there isn't one.
+func indexOf(objs []planObject, name string) int {
+	for i, o := range objs {
+		if o.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
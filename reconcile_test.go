@@ -0,0 +1,255 @@
+package panos
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newFakeConfig returns a session for a fake device whose candidate configuration of the vsys1 objects is the
+// given XML, by object type, i.e. "address" -> "<entry ...>...</entry>". Everything else returns success.
+func newFakeConfig(t *testing.T, config map[string]string) (*PaloAlto, *fakeDevice) {
+	return newFakeDevice(t, func(q url.Values) string {
+		if q.Get("action") != "get" {
+			return success
+		}
+
+		for objecttype, path := range planPaths {
+			if strings.HasSuffix(q.Get("xpath"), "/vsys/entry[@name='vsys1']/"+path) {
+				return `<response status="success"><result><` + objecttype + `>` + config[objecttype] + `</` + objecttype + `></result></response>`
+			}
+		}
+
+		return `<response status="error" code="7"><msg><line>No such node</line></msg></response>`
+	})
+}
+
+func TestPlanOrder(t *testing.T) {
+	p, _ := newFakeConfig(t, map[string]string{
+		"address":       `<entry name="old"><fqdn>old.example.com</fqdn></entry>`,
+		"address-group": `<entry name="old-group"><static><member>old</member></static></entry>`,
+	})
+
+	desired := &DesiredState{
+		Addresses: []Address{{Name: "web", IPAddress: "10.1.1.1", Tags: []string{"prod"}}},
+		AddressGroups: []AddressGroup{
+			{Name: "all", Members: []string{"servers", "web"}},
+			{Name: "servers", Members: []string{"web"}},
+		},
+		Services:      []Service{{Name: "http", TCPPort: "80"}},
+		ServiceGroups: []ServiceGroup{{Name: "web-ports", Members: []string{"http"}}},
+		Tags:          []Tag{{Name: "prod", Color: "Red"}},
+		Prune:         true,
+	}
+
+	plan, err := p.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"create tag prod",
+		"create address web",
+		"create service http",
+		"create address-group servers",
+		"create address-group all",
+		"create service-group web-ports",
+		"delete address-group old-group",
+		"delete address old",
+	}, "\n")
+
+	if plan.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", plan, want)
+	}
+}
+
+func TestPlanGroupCycle(t *testing.T) {
+	p, _ := newFakeConfig(t, nil)
+
+	desired := &DesiredState{AddressGroups: []AddressGroup{{Name: "a", Members: []string{"b"}}, {Name: "b", Members: []string{"a"}}}}
+	if _, err := p.Plan(desired); err == nil || !strings.Contains(err.Error(), "contains itself") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestPlanKeepsUnmanagedSettings(t *testing.T) {
+	p, _ := newFakeConfig(t, map[string]string{
+		"tag": `<entry name="prod"><color>color1</color></entry><entry name="lab"><comments>lab</comments></entry>`,
+		"service": `<entry name="http"><protocol><tcp><port>80</port><override><yes><timeout>7200</timeout></yes></override>
+			</tcp></protocol><disable-override>yes</disable-override></entry>`,
+		"address-group": `<entry name="servers"><static><member>b</member><member>a</member></static>
+			<tag><member>prod</member></tag></entry>`,
+	})
+
+	desired := &DesiredState{
+		Tags:          []Tag{{Name: "prod", Color: "Red"}, {Name: "lab", Comments: "lab"}},
+		Services:      []Service{{Name: "http", TCPPort: "80"}},
+		AddressGroups: []AddressGroup{{Name: "servers", Members: []string{"a", "b"}}},
+	}
+
+	plan, err := p.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Changes) != 0 {
+		t.Errorf("got:\n%s", plan)
+	}
+}
+
+func TestApplyPlanMergesEdits(t *testing.T) {
+	p, d := newFakeConfig(t, map[string]string{
+		"address":       `<entry name="web"><fqdn>web.example.com</fqdn><tag><member>prod</member></tag></entry>`,
+		"address-group": `<entry name="servers"><static><member>a</member><member>b</member></static><tag><member>prod</member></tag></entry>`,
+	})
+
+	desired := &DesiredState{
+		Addresses:     []Address{{Name: "web", IPAddress: "10.1.1.1"}},
+		AddressGroups: []AddressGroup{{Name: "servers", Members: []string{"a", "c"}}},
+	}
+
+	plan, err := p.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.String() != "edit address web\nedit address-group servers" {
+		t.Fatalf("got:\n%s", plan)
+	}
+
+	if err := p.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(d.sent("edit")); n != 0 {
+		t.Errorf("sent %d edits, changes should be merged", n)
+	}
+
+	var got []string
+	for _, q := range d.sent("") {
+		if a := q.Get("action"); a == "set" || a == "delete" {
+			got = append(got, a+" "+q.Get("xpath")[strings.LastIndex(q.Get("xpath"), "vsys1']/")+8:]+" "+q.Get("element"))
+		}
+	}
+
+	want := []string{
+		"delete address/entry[@name='web']/fqdn ",
+		`set address <entry name="web"><ip-netmask>10.1.1.1</ip-netmask></entry>`,
+		`set address-group <entry name="servers"><static><member>a</member><member>c</member></static></entry>`,
+		"delete address-group/entry[@name='servers']/static/member[text()='b'] ",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPlanURLCategoryType(t *testing.T) {
+	p, _ := newFakeConfig(t, map[string]string{
+		"url-category": `<entry name="blocked"><list><member>gambling</member></list><type>Category Match</type></entry>`,
+	})
+
+	desired := &DesiredState{URLCategories: []CustomURL{
+		{Name: "blocked", Members: []string{"gambling", "malware"}},
+		{Name: "allowed", Members: []string{"example.com"}},
+	}}
+
+	plan, err := p.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Changes) != 2 {
+		t.Fatalf("got:\n%s", plan)
+	}
+
+	for _, c := range plan.Changes {
+		x := c.element.(*xmlCustomURLEntry)
+
+		switch c.Name {
+		case "allowed":
+			if c.Action != "create" || x.Type != "URL List" {
+				t.Errorf("got %s with type %q", c, x.Type)
+			}
+		case "blocked":
+			if c.Action != "edit" || x.Type != "" {
+				t.Errorf("got %s with type %q", c, x.Type)
+			}
+		}
+	}
+
+	p.SoftwareVersion = "8.1.0"
+
+	plan, err = p.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x := plan.Changes[1].element.(*xmlCustomURLEntry); plan.Changes[1].Name != "allowed" || x.Type != "" {
+		t.Errorf("got %s with type %q", plan.Changes[1], x.Type)
+	}
+}
+
+func TestPlanRename(t *testing.T) {
+	p, d := newFakeConfig(t, map[string]string{
+		"address": `<entry name="old"><ip-netmask>10.1.1.1</ip-netmask></entry>`,
+	})
+
+	desired := &DesiredState{
+		Addresses: []Address{{Name: "new", IPAddress: "10.1.1.1"}},
+		Renames:   []Rename{{ObjectType: "address", From: "old", To: "new"}},
+		Prune:     true,
+	}
+
+	plan, err := p.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.String() != "rename address old to new" {
+		t.Fatalf("got:\n%s", plan)
+	}
+
+	if err := p.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if q := d.sent("rename")[0]; !strings.HasSuffix(q.Get("xpath"), "/address/entry[@name='old']") || q.Get("newname") != "new" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
+
+func TestPlanRenameReferences(t *testing.T) {
+	p, d := newFakeConfig(t, map[string]string{
+		"tag":           `<entry name="old-tag"/>`,
+		"address":       `<entry name="old"><ip-netmask>10.1.1.1</ip-netmask><tag><member>old-tag</member></tag></entry>`,
+		"address-group": `<entry name="servers"><static><member>old</member><member>db</member></static></entry>`,
+	})
+
+	desired := &DesiredState{
+		Addresses:     []Address{{Name: "new", IPAddress: "10.1.1.1", Tags: []string{"new-tag"}}},
+		AddressGroups: []AddressGroup{{Name: "servers", Members: []string{"new", "db"}}},
+		Tags:          []Tag{{Name: "new-tag"}},
+		Renames: []Rename{
+			{ObjectType: "address", From: "old", To: "new"},
+			{ObjectType: "tag", From: "old-tag", To: "new-tag"},
+		},
+	}
+
+	plan, err := p.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "rename address old to new\nrename tag old-tag to new-tag"; plan.String() != want {
+		t.Fatalf("got:\n%s", plan)
+	}
+
+	if err := p.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(d.sent("delete")); n != 0 {
+		t.Errorf("sent %d deletes, the device renames the references itself", n)
+	}
+}
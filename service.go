@@ -1,12 +1,11 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"strings"
-
-	"github.com/scottdware/go-rested"
 )
 
 // ServiceObjects contains a slice of all service objects.
@@ -17,12 +16,68 @@ type ServiceObjects struct {
 	Services []Service `xml:"result>service>entry"`
 }
 
-// Service contains information about each individual service object.
+// Service contains information about each individual service object. Only one of TCPPort, UDPPort or SCTPPort
+// is set, depending on the protocol, and SourcePort applies to that same protocol. Timeout, HalfcloseTimeout
+// and TimewaitTimeout override the default session timeouts (in seconds) for TCP services, and only Timeout can
+// be overridden for UDP services. DisableOverride is only used on Panorama, and is one of: yes or no. Since the
+// source port and timeouts are nested under the protocol, they have no xml tags of their own, and are filled in by
+// Services.
 type Service struct {
-	Name        string `xml:"name,attr"`
-	TCPPort     string `xml:"protocol>tcp>port,omitempty"`
-	UDPPort     string `xml:"protocol>udp>port,omitempty"`
-	Description string `xml:"description,omitempty"`
+	Name             string   `xml:"name,attr"`
+	TCPPort          string   `xml:"protocol>tcp>port,omitempty"`
+	UDPPort          string   `xml:"protocol>udp>port,omitempty"`
+	SCTPPort         string   `xml:"protocol>sctp>port,omitempty"`
+	SourcePort       string   `xml:"-"`
+	Timeout          string   `xml:"-"`
+	HalfcloseTimeout string   `xml:"-"`
+	TimewaitTimeout  string   `xml:"-"`
+	Description      string   `xml:"description,omitempty"`
+	Tags             []string `xml:"tag>member,omitempty"`
+	DisableOverride  string   `xml:"disable-override,omitempty"`
+}
+
+// xmlServiceObjects is used for parsing all service objects.
+type xmlServiceObjects struct {
+	XMLName  xml.Name     `xml:"response"`
+	Status   string       `xml:"status,attr"`
+	Code     string       `xml:"code,attr"`
+	Services []xmlService `xml:"result>service>entry"`
+}
+
+// xmlService is used for parsing, creating or editing a service object.
+type xmlService struct {
+	XMLName         xml.Name           `xml:"entry"`
+	Name            string             `xml:"name,attr"`
+	Protocol        xmlServiceProtocol `xml:"protocol"`
+	Description     string             `xml:"description,omitempty"`
+	Tags            *xmlMembers        `xml:"tag,omitempty"`
+	DisableOverride string             `xml:"disable-override,omitempty"`
+}
+
+// xmlServiceProtocol is used for parsing the protocol settings of a service object.
+type xmlServiceProtocol struct {
+	TCP  *xmlServicePort `xml:"tcp,omitempty"`
+	UDP  *xmlServicePort `xml:"udp,omitempty"`
+	SCTP *xmlServicePort `xml:"sctp,omitempty"`
+}
+
+// xmlServicePort is used for parsing the ports and timeout overrides of a service object.
+type xmlServicePort struct {
+	Port       string              `xml:"port"`
+	SourcePort string              `xml:"source-port,omitempty"`
+	Override   *xmlServiceOverride `xml:"override,omitempty"`
+}
+
+// xmlServiceOverride is used for parsing the session timeout overrides of a service object.
+type xmlServiceOverride struct {
+	Yes *xmlServiceTimeouts `xml:"yes,omitempty"`
+}
+
+// xmlServiceTimeouts is used for parsing each session timeout override.
+type xmlServiceTimeouts struct {
+	Timeout          string `xml:"timeout,omitempty"`
+	HalfcloseTimeout string `xml:"halfclose-timeout,omitempty"`
+	TimewaitTimeout  string `xml:"timewait-timeout,omitempty"`
 }
 
 // ServiceGroups contains a slice of all service groups.
@@ -40,27 +95,132 @@ type ServiceGroup struct {
 	Description string   `xml:"description,omitempty"`
 }
 
-// Services returns information about all of the service objects. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all objects are returned.
-func (p *PaloAlto) Services(devicegroup ...string) (*ServiceObjects, error) {
-	var svcs ServiceObjects
-	xpath := "/config/devices/entry//service"
-	r := rested.NewRequest()
+// Protocol returns the protocol of the service object, which is one of: tcp, udp or sctp.
+func (s *Service) Protocol() string {
+	switch {
+	case s.TCPPort != "":
+		return "tcp"
+	case s.UDPPort != "":
+		return "udp"
+	case s.SCTPPort != "":
+		return "sctp"
+	}
+
+	return ""
+}
 
-	if p.DeviceType != "panorama" && len(devicegroup) > 0 {
-		return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
+// toXML converts the service object into the form used when creating or editing it, and makes sure that
+// exactly one protocol is set, along with only the timeouts it supports.
+func (s *Service) toXML() (*xmlService, error) {
+	set := 0
+	port := &xmlServicePort{SourcePort: strings.Replace(s.SourcePort, " ", "", -1)}
+	x := &xmlService{
+		Name:            s.Name,
+		Description:     s.Description,
+		Tags:            newMembers(s.Tags...),
+		DisableOverride: s.DisableOverride,
 	}
 
+	for _, v := range []string{s.TCPPort, s.UDPPort, s.SCTPPort} {
+		if v != "" {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("service object %s must have exactly one of: a tcp, udp or sctp port", s.Name)
+	}
+
+	if s.Timeout != "" || s.HalfcloseTimeout != "" || s.TimewaitTimeout != "" {
+		port.Override = &xmlServiceOverride{Yes: &xmlServiceTimeouts{
+			Timeout:          s.Timeout,
+			HalfcloseTimeout: s.HalfcloseTimeout,
+			TimewaitTimeout:  s.TimewaitTimeout,
+		}}
+	}
+
+	switch s.Protocol() {
+	case "tcp":
+		port.Port = strings.Replace(s.TCPPort, " ", "", -1)
+		x.Protocol.TCP = port
+	case "udp":
+		if s.HalfcloseTimeout != "" || s.TimewaitTimeout != "" {
+			return nil, fmt.Errorf("service object %s can only override the timeout for the udp protocol", s.Name)
+		}
+
+		port.Port = strings.Replace(s.UDPPort, " ", "", -1)
+		x.Protocol.UDP = port
+	case "sctp":
+		if port.Override != nil {
+			return nil, fmt.Errorf("service object %s cannot override timeouts for the sctp protocol", s.Name)
+		}
+
+		port.Port = strings.Replace(s.SCTPPort, " ", "", -1)
+		x.Protocol.SCTP = port
+	}
+
+	return x, nil
+}
+
+// service converts the parsed service object into a Service.
+func (x *xmlService) service() Service {
+	var port *xmlServicePort
+	s := Service{
+		Name:            x.Name,
+		Description:     x.Description,
+		Tags:            x.Tags.list(),
+		DisableOverride: x.DisableOverride,
+	}
+
+	switch {
+	case x.Protocol.TCP != nil:
+		port = x.Protocol.TCP
+		s.TCPPort = port.Port
+	case x.Protocol.UDP != nil:
+		port = x.Protocol.UDP
+		s.UDPPort = port.Port
+	case x.Protocol.SCTP != nil:
+		port = x.Protocol.SCTP
+		s.SCTPPort = port.Port
+	default:
+		return s
+	}
+
+	s.SourcePort = port.SourcePort
+
+	if port.Override != nil && port.Override.Yes != nil {
+		s.Timeout = port.Override.Yes.Timeout
+		s.HalfcloseTimeout = port.Override.Yes.HalfcloseTimeout
+		s.TimewaitTimeout = port.Override.Yes.TimewaitTimeout
+	}
+
+	return s
+}
+
+// Services returns information about all of the service objects. You can (optionally) specify a Location as the last
+// parameter, such as a device-group when ran against a Panorama device. If no location is specified, then all
+// objects are returned. When ran against a firewall, you can (optionally) specify a VsysLocation instead.
+func (p *PaloAlto) Services(loc ...Location) (*ServiceObjects, error) {
+	return p.ServicesContext(context.Background(), loc...)
+}
+
+// ServicesContext is the same as Services, but uses the given context for all API requests.
+func (p *PaloAlto) ServicesContext(ctx context.Context, loc ...Location) (*ServiceObjects, error) {
+	var parsedSvcs xmlServiceObjects
+	var svcs ServiceObjects
+	xpath := "/config/devices/entry//service"
+
 	if p.DeviceType == "panos" && p.Panorama == true {
 		xpath = "/config/panorama//service"
 	}
 
-	if p.DeviceType == "panos" && p.Panorama == false {
-		xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service"
-	}
+	if (p.DeviceType == "panos" && p.Panorama == false) || len(loc) > 0 {
+		base, err := p.locationXpath(loc)
+		if err != nil {
+			return nil, err
+		}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service", devicegroup[0])
+		xpath = base + "/service"
 	}
 
 	query := map[string]string{
@@ -69,40 +229,51 @@ func (p *PaloAlto) Services(devicegroup ...string) (*ServiceObjects, error) {
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	svcData := r.Send("get", p.URI, nil, headers, query)
+	svcData := p.send(ctx, "get", query)
+	if svcData.Error != nil {
+		return nil, svcData.Error
+	}
 
-	if err := xml.Unmarshal(svcData.Body, &svcs); err != nil {
+	if err := xml.Unmarshal(svcData.Body, &parsedSvcs); err != nil {
 		return nil, err
 	}
 
-	if svcs.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", svcs.Code, errorCodes[svcs.Code])
+	if parsedSvcs.Status != "success" {
+		return nil, newAPIError(svcData, query)
+	}
+
+	svcs.XMLName, svcs.Status, svcs.Code = parsedSvcs.XMLName, parsedSvcs.Status, parsedSvcs.Code
+
+	for _, s := range parsedSvcs.Services {
+		svcs.Services = append(svcs.Services, s.service())
 	}
 
 	return &svcs, nil
 }
 
-// ServiceGroups returns information about all of the service groups. You can (optionally) specify a device-group
-// when ran against a Panorama device. If no device-group is specified, then all service groups are returned.
-func (p *PaloAlto) ServiceGroups(devicegroup ...string) (*ServiceGroups, error) {
+// ServiceGroups returns information about all of the service groups. You can (optionally) specify a Location as the
+// last parameter, such as a device-group when ran against a Panorama device. If no location is specified, then all
+// service groups are returned. When ran against a firewall, you can (optionally) specify a VsysLocation instead.
+func (p *PaloAlto) ServiceGroups(loc ...Location) (*ServiceGroups, error) {
+	return p.ServiceGroupsContext(context.Background(), loc...)
+}
+
+// ServiceGroupsContext is the same as ServiceGroups, but uses the given context for all API requests.
+func (p *PaloAlto) ServiceGroupsContext(ctx context.Context, loc ...Location) (*ServiceGroups, error) {
 	var groups ServiceGroups
 	xpath := "/config/devices/entry//service-group"
-	r := rested.NewRequest()
-
-	if p.DeviceType != "panorama" && len(devicegroup) > 0 {
-		return nil, errors.New("you must be connected to a Panorama device when specifying a device-group")
-	}
 
 	if p.DeviceType == "panos" && p.Panorama == true {
 		xpath = "/config/panorama//service-group"
 	}
 
-	if p.DeviceType == "panos" && p.Panorama == false {
-		xpath = "/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group"
-	}
+	if (p.DeviceType == "panos" && p.Panorama == false) || len(loc) > 0 {
+		base, err := p.locationXpath(loc)
+		if err != nil {
+			return nil, err
+		}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group", devicegroup[0])
+		xpath = base + "/service-group"
 	}
 
 	query := map[string]string{
@@ -111,49 +282,61 @@ func (p *PaloAlto) ServiceGroups(devicegroup ...string) (*ServiceGroups, error)
 		"xpath":  xpath,
 		"key":    p.Key,
 	}
-	groupData := r.Send("get", p.URI, nil, headers, query)
+	groupData := p.send(ctx, "get", query)
+	if groupData.Error != nil {
+		return nil, groupData.Error
+	}
 
 	if err := xml.Unmarshal(groupData.Body, &groups); err != nil {
 		return nil, err
 	}
 
 	if groups.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", groups.Code, errorCodes[groups.Code])
+		return nil, newAPIError(groupData, query)
 	}
 
 	return &groups, nil
 }
 
-// CreateService adds a new service object to the device. Port can be a single port #, range (1-65535), or comma separated (80, 8080, 443).
-// If creating a service object on a Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) CreateService(name, protocol, port, description string, devicegroup ...string) error {
+// CreateService adds a new service object to the device. protocol should be one of: tcp, udp or sctp. Port can be a
+// single port #, range (1-65535), or comma separated (80, 8080, 443). If creating a service object on a Panorama
+// device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall,
+// you can specify a VsysLocation instead.
+func (p *PaloAlto) CreateService(name, protocol, port, description string, loc ...Location) error {
+	return p.CreateServiceContext(context.Background(), name, protocol, port, description, loc...)
+}
+
+// CreateServiceContext is the same as CreateService, but uses the given context for all API requests.
+func (p *PaloAlto) CreateServiceContext(ctx context.Context, name, protocol, port, description string, loc ...Location) error {
 	var xmlBody string
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
+
+	if err := validateName(name); err != nil {
+		return err
+	}
 
 	switch protocol {
 	case "tcp":
-		xmlBody = fmt.Sprintf("<protocol><tcp><port>%s</port></tcp></protocol>", strings.Replace(port, " ", "", -1))
+		xmlBody = fmt.Sprintf("<protocol><tcp><port>%s</port></tcp></protocol>", escapeXML(strings.Replace(port, " ", "", -1)))
 	case "udp":
-		xmlBody = fmt.Sprintf("<protocol><udp><port>%s</port></udp></protocol>", strings.Replace(port, " ", "", -1))
+		xmlBody = fmt.Sprintf("<protocol><udp><port>%s</port></udp></protocol>", escapeXML(strings.Replace(port, " ", "", -1)))
+	case "sctp":
+		xmlBody = fmt.Sprintf("<protocol><sctp><port>%s</port></sctp></protocol>", escapeXML(strings.Replace(port, " ", "", -1)))
+	default:
+		return fmt.Errorf("unknown protocol %s, should be one of: tcp, udp or sctp", protocol)
 	}
 
 	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
-	}
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']", name)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/service/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":    "config",
@@ -163,7 +346,7 @@ func (p *PaloAlto) CreateService(name, protocol, port, description string, devic
 		"key":     p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -173,93 +356,125 @@ func (p *PaloAlto) CreateService(name, protocol, port, description string, devic
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
 }
 
-// CreateSharedService adds a new shared service object to Panorama. Port can be a single port #, range (1-65535), or comma separated (80, 8080, 443).
-func (p *PaloAlto) CreateSharedService(name, protocol, port, description string) error {
-	var xmlBody string
+// EditService replaces an existing service object with the given one, matching it by name. Since the object is
+// updated in place, any groups or rules that reference it are left intact. The entire object is replaced, so include
+// any tags you want to keep - editing an object returned by Services keeps everything as-is. If editing a service
+// object on a Panorama device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a
+// multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) EditService(svc *Service, loc ...Location) error {
+	return p.EditServiceContext(context.Background(), svc, loc...)
+}
+
+// EditServiceContext is the same as EditService, but uses the given context for all API requests.
+func (p *PaloAlto) EditServiceContext(ctx context.Context, svc *Service, loc ...Location) error {
 	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
 
-	switch protocol {
-	case "tcp":
-		xmlBody = fmt.Sprintf("<protocol><tcp><port>%s</port></tcp></protocol>", strings.Replace(port, " ", "", -1))
-	case "udp":
-		xmlBody = fmt.Sprintf("<protocol><udp><port>%s</port></udp></protocol>", strings.Replace(port, " ", "", -1))
+	if svc == nil || svc.Name == "" {
+		return errors.New("you must specify the name of the service object to edit")
 	}
 
-	if description != "" {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+	x, err := svc.toXML()
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panos" {
-		return errors.New("you can only create shared objects when connected to a Panorama device")
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']", name)
+	xpath = base + "/service"
+
+	return p.setEntry(ctx, "edit", xpath, svc.Name, x)
+}
+
+// CreateServiceObject adds a new service object to the device from the given Service, which unlike CreateService
+// lets you also set the source port, session timeout overrides and tags when the object is created. If creating a
+// service object on a Panorama device, then specify its location (i.e. a DeviceGroupLocation) as the last parameter.
+// On a multi-vsys firewall, you can specify a VsysLocation instead.
+func (p *PaloAlto) CreateServiceObject(svc *Service, loc ...Location) error {
+	return p.CreateServiceObjectContext(context.Background(), svc, loc...)
+}
+
+// CreateServiceObjectContext is the same as CreateServiceObject, but uses the given context for all API requests.
+func (p *PaloAlto) CreateServiceObjectContext(ctx context.Context, svc *Service, loc ...Location) error {
+	if svc == nil || svc.Name == "" {
+		return errors.New("you must specify a name for the service object")
 	}
 
-	query := map[string]string{
-		"type":    "config",
-		"action":  "set",
-		"xpath":   xpath,
-		"element": xmlBody,
-		"key":     p.Key,
+	if err := validateName(svc.Name); err != nil {
+		return err
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
+	x, err := svc.toXML()
+	if err != nil {
+		return err
 	}
 
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
+	base, err := p.locationXpath(loc)
+	if err != nil {
 		return err
 	}
 
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+	return p.setEntry(ctx, "set", base+"/service", svc.Name, x)
+}
+
+// CreateSharedService adds a new shared service object to Panorama. protocol should be one of: tcp, udp or sctp. Port can be
+// a single port #, range (1-65535), or comma separated (80, 8080, 443).
+func (p *PaloAlto) CreateSharedService(name, protocol, port, description string) error {
+	return p.CreateSharedServiceContext(context.Background(), name, protocol, port, description)
+}
+
+// CreateSharedServiceContext is the same as CreateSharedService, but uses the given context for all API requests.
+func (p *PaloAlto) CreateSharedServiceContext(ctx context.Context, name, protocol, port, description string) error {
+	if p.DeviceType == "panos" {
+		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	return nil
+	return p.CreateServiceContext(ctx, name, protocol, port, description, SharedLocation())
 }
 
-// CreateServiceGroup will create a new service group on the device. You can specify multiple members
-// by separating them with a comma, i.e. "tcp-ports, udp-ports". If creating a service group on
-// a Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) CreateServiceGroup(name, members string, devicegroup ...string) error {
+// CreateServiceGroup will create a new service group on the device. You can specify multiple members by separating
+// them with a comma, i.e. "tcp-ports, udp-ports". If creating a service group on a Panorama device, then specify its
+// location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can specify a
+// VsysLocation instead.
+func (p *PaloAlto) CreateServiceGroup(name, members string, loc ...Location) error {
+	return p.CreateServiceGroupContext(context.Background(), name, members, loc...)
+}
+
+// CreateServiceGroupContext is the same as CreateServiceGroup, but uses the given context for all API requests.
+func (p *PaloAlto) CreateServiceGroupContext(ctx context.Context, name, members string, loc ...Location) error {
 	var xmlBody string
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 	m := strings.Split(members, ",")
 
+	if err := validateName(name); err != nil {
+		return err
+	}
+
 	if members == "" {
 		return errors.New("you cannot create a service group without any members")
 	}
 
 	xmlBody = "<members>"
 	for _, member := range m {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(member))
+		xmlBody += fmt.Sprintf("<member>%s</member>", escapeXML(strings.TrimSpace(member)))
 	}
 	xmlBody += "</members>"
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/service-group/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":    "config",
@@ -269,7 +484,7 @@ func (p *PaloAlto) CreateServiceGroup(name, members string, devicegroup ...strin
 		"key":     p.Key,
 	}
 
-	resp := r.Send("post", p.URI, nil, nil, query)
+	resp := p.send(ctx, "post", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -279,7 +494,7 @@ func (p *PaloAlto) CreateServiceGroup(name, members string, devicegroup ...strin
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -288,72 +503,36 @@ func (p *PaloAlto) CreateServiceGroup(name, members string, devicegroup ...strin
 // CreateSharedServiceGroup will create a new shared service group on Panorama. You can specify multiple members
 // by separating them with a comma, i.e. "tcp-ports, udp-ports".
 func (p *PaloAlto) CreateSharedServiceGroup(name, members string) error {
-	var xmlBody string
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
-	m := strings.Split(members, ",")
-
-	if members == "" {
-		return errors.New("you cannot create a service group without any members")
-	}
-
-	xmlBody = "<members>"
-	for _, member := range m {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(member))
-	}
-	xmlBody += "</members>"
+	return p.CreateSharedServiceGroupContext(context.Background(), name, members)
+}
 
+// CreateSharedServiceGroupContext is the same as CreateSharedServiceGroup, but uses the given context for all API requests.
+func (p *PaloAlto) CreateSharedServiceGroupContext(ctx context.Context, name, members string) error {
 	if p.DeviceType == "panos" {
 		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']", name)
-	}
-
-	query := map[string]string{
-		"type":    "config",
-		"action":  "set",
-		"xpath":   xpath,
-		"element": xmlBody,
-		"key":     p.Key,
-	}
-
-	resp := r.Send("post", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
+	return p.CreateServiceGroupContext(ctx, name, members, SharedLocation())
+}
 
-	return nil
+// DeleteService will remove a service object from the device. If deleting a service object on a Panorama device,
+// then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can
+// specify a VsysLocation instead.
+func (p *PaloAlto) DeleteService(name string, loc ...Location) error {
+	return p.DeleteServiceContext(context.Background(), name, loc...)
 }
 
-// DeleteService will remove a service object from the device. If deleting a service object on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) DeleteService(name string, devicegroup ...string) error {
+// DeleteServiceContext is the same as DeleteService, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteServiceContext(ctx context.Context, name string, loc ...Location) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
 
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service/entry[@name='%s']", name)
-	}
-
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/service/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":   "config",
@@ -362,7 +541,7 @@ func (p *PaloAlto) DeleteService(name string, devicegroup ...string) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -372,7 +551,7 @@ func (p *PaloAlto) DeleteService(name string, devicegroup ...string) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -380,59 +559,36 @@ func (p *PaloAlto) DeleteService(name string, devicegroup ...string) error {
 
 // DeleteSharedService will remove a shared service object from Panorama.
 func (p *PaloAlto) DeleteSharedService(name string) error {
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
+	return p.DeleteSharedServiceContext(context.Background(), name)
+}
 
+// DeleteSharedServiceContext is the same as DeleteSharedService, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteSharedServiceContext(ctx context.Context, name string) error {
 	if p.DeviceType == "panos" {
 		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/service/entry[@name='%s']", name)
-	}
-
-	query := map[string]string{
-		"type":   "config",
-		"action": "delete",
-		"xpath":  xpath,
-		"key":    p.Key,
-	}
-
-	resp := r.Send("get", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
+	return p.DeleteServiceContext(ctx, name, SharedLocation())
+}
 
-	return nil
+// DeleteServiceGroup will remove a service group from the device. If deleting a service group on a Panorama device,
+// then specify its location (i.e. a DeviceGroupLocation) as the last parameter. On a multi-vsys firewall, you can
+// specify a VsysLocation instead.
+func (p *PaloAlto) DeleteServiceGroup(name string, loc ...Location) error {
+	return p.DeleteServiceGroupContext(context.Background(), name, loc...)
 }
 
-// DeleteServiceGroup will remove a service group from the device. If deleting a service group on a
-// Panorama device, then specify the given device-group name as the last parameter.
-func (p *PaloAlto) DeleteServiceGroup(name string, devicegroup ...string) error {
+// DeleteServiceGroupContext is the same as DeleteServiceGroup, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteServiceGroupContext(ctx context.Context, name string, loc ...Location) error {
 	var xpath string
 	var reqError requestError
-	r := rested.NewRequest()
-
-	if p.DeviceType == "panos" {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/vsys/entry[@name='vsys1']/service-group/entry[@name='%s']", name)
-	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) > 0 {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/device-group/entry[@name='%s']/service-group/entry[@name='%s']", devicegroup[0], name)
+	base, err := p.locationXpath(loc)
+	if err != nil {
+		return err
 	}
 
-	if p.DeviceType == "panorama" && len(devicegroup) <= 0 {
-		return errors.New("you must specify a device-group when connected to a Panorama device")
-	}
+	xpath = fmt.Sprintf("%s/service-group/entry[@name=%s]", base, xpathLiteral(name))
 
 	query := map[string]string{
 		"type":   "config",
@@ -441,7 +597,7 @@ func (p *PaloAlto) DeleteServiceGroup(name string, devicegroup ...string) error
 		"key":    p.Key,
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -451,7 +607,7 @@ func (p *PaloAlto) DeleteServiceGroup(name string, devicegroup ...string) error
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -459,37 +615,14 @@ func (p *PaloAlto) DeleteServiceGroup(name string, devicegroup ...string) error
 
 // DeleteSharedServiceGroup will remove a shared service group from Panorama.
 func (p *PaloAlto) DeleteSharedServiceGroup(name string) error {
-	var xpath string
-	var reqError requestError
-	r := rested.NewRequest()
+	return p.DeleteSharedServiceGroupContext(context.Background(), name)
+}
 
+// DeleteSharedServiceGroupContext is the same as DeleteSharedServiceGroup, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteSharedServiceGroupContext(ctx context.Context, name string) error {
 	if p.DeviceType == "panos" {
 		return errors.New("you can only create shared objects when connected to a Panorama device")
 	}
 
-	if p.DeviceType == "panorama" {
-		xpath = fmt.Sprintf("/config/shared/service-group/entry[@name='%s']", name)
-	}
-
-	query := map[string]string{
-		"type":   "config",
-		"action": "delete",
-		"xpath":  xpath,
-		"key":    p.Key,
-	}
-
-	resp := r.Send("get", p.URI, nil, nil, query)
-	if resp.Error != nil {
-		return resp.Error
-	}
-
-	if err := xml.Unmarshal(resp.Body, &reqError); err != nil {
-		return err
-	}
-
-	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
-	}
-
-	return nil
+	return p.DeleteServiceGroupContext(ctx, name, SharedLocation())
 }
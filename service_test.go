@@ -0,0 +1,143 @@
+package panos
+
+import (
+	"encoding/xml"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServiceToXML(t *testing.T) {
+	tests := []struct {
+		svc  Service
+		want string
+		ok   bool
+	}{
+		{
+			Service{Name: "web", TCPPort: "80, 443", SourcePort: "1024-65535", Timeout: "7200", HalfcloseTimeout: "60"},
+			`<entry name="web"><protocol><tcp><port>80,443</port><source-port>1024-65535</source-port><override><yes>` +
+				`<timeout>7200</timeout><halfclose-timeout>60</halfclose-timeout></yes></override></tcp></protocol></entry>`,
+			true,
+		},
+		{
+			Service{Name: "dns", UDPPort: "53", Timeout: "30", Description: "a&b", Tags: []string{"infra"}},
+			`<entry name="dns"><protocol><udp><port>53</port><override><yes><timeout>30</timeout></yes></override></udp></protocol>` +
+				`<description>a&amp;b</description><tag><member>infra</member></tag></entry>`,
+			true,
+		},
+		{Service{Name: "none"}, "", false},
+		{Service{Name: "two", TCPPort: "80", UDPPort: "53"}, "", false},
+		{Service{Name: "udp", UDPPort: "53", TimewaitTimeout: "10"}, "", false},
+		{Service{Name: "sctp", SCTPPort: "2905", Timeout: "10"}, "", false},
+	}
+
+	for _, tt := range tests {
+		x, err := tt.svc.toXML()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.svc.Name, err)
+			continue
+		}
+
+		if !tt.ok {
+			continue
+		}
+
+		if b, _ := xml.Marshal(x); string(b) != tt.want {
+			t.Errorf("%s: got %s", tt.svc.Name, b)
+		}
+	}
+}
+
+func TestServicesParse(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result><service><entry name="web"><protocol><tcp><port>80</port>` +
+			`<source-port>1024</source-port><override><yes><timeout>7200</timeout><timewait-timeout>5</timewait-timeout></yes></override>` +
+			`</tcp></protocol><tag><member>prod</member></tag></entry></service></result></response>`
+	})
+
+	svcs, err := p.Services()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Service{Name: "web", TCPPort: "80", SourcePort: "1024", Timeout: "7200", TimewaitTimeout: "5", Tags: []string{"prod"}}
+	if len(svcs.Services) != 1 || !reflect.DeepEqual(svcs.Services[0], want) {
+		t.Errorf("got %+v", svcs.Services)
+	}
+
+	if svcs.XMLName.Local != "response" || svcs.Status != "success" {
+		t.Errorf("got %+v", svcs)
+	}
+}
+
+func TestCreateServiceObject(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+	p.DeviceType = "panorama"
+
+	svc := &Service{Name: "web", TCPPort: "8080", SourcePort: "1024-65535", Timeout: "7200"}
+	if err := p.CreateServiceObject(svc, DeviceGroupLocation("lab")); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := d.sent("")
+	if len(sent) != 1 || sent[0].Get("action") != "set" {
+		t.Fatalf("got %v", sent)
+	}
+
+	if xpath := sent[0].Get("xpath"); !strings.HasSuffix(xpath, "/device-group/entry[@name='lab']/service") {
+		t.Errorf("unexpected xpath %s", xpath)
+	}
+
+	if element := sent[0].Get("element"); !strings.Contains(element, "<source-port>1024-65535</source-port><override><yes><timeout>7200</timeout>") {
+		t.Errorf("unexpected element %s", element)
+	}
+
+	for _, bad := range []*Service{nil, {Name: "web"}, {Name: "bad name!", TCPPort: "80"}} {
+		if err := p.CreateServiceObject(bad); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+
+	if len(d.sent("")) != 1 {
+		t.Error("invalid service objects should not be sent")
+	}
+}
+
+func TestCreateService(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	if err := p.CreateService("web", "tcp", "80, 443", "web & ssl"); err != nil {
+		t.Fatal(err)
+	}
+
+	if element := d.sent("set")[0].Get("element"); element != "<protocol><tcp><port>80,443</port></tcp></protocol><description>web &amp; ssl</description>" {
+		t.Errorf("unexpected element %s", element)
+	}
+
+	if err := p.CreateService("web", "icmp", "0", ""); err == nil {
+		t.Error("expected an error for an unknown protocol")
+	}
+}
+
+func TestEditService(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	svc := &Service{Name: "dns", UDPPort: "53", Description: "resolvers"}
+	if err := p.EditService(svc, VsysLocation("vsys2")); err != nil {
+		t.Fatal(err)
+	}
+
+	q := d.sent("edit")[0]
+	if want := "/vsys/entry[@name='vsys2']/service/entry[@name='dns']"; !strings.HasSuffix(q.Get("xpath"), want) {
+		t.Errorf("got xpath %s", q.Get("xpath"))
+	}
+
+	if want := `<entry name="dns"><protocol><udp><port>53</port></udp></protocol><description>resolvers</description></entry>`; q.Get("element") != want {
+		t.Errorf("got element %s", q.Get("element"))
+	}
+
+	if err := p.EditService(&Service{}); err == nil || len(d.sent("edit")) != 1 {
+		t.Error("expected an error for a service without a name")
+	}
+}
@@ -0,0 +1,275 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SessionOption configures how a session communicates with the device. Options are passed as the
+// last parameters to NewSession, i.e. NewSession("fw", "admin", "paloalto", panos.WithTimeout(30*time.Second)).
+type SessionOption func(*sessionConfig) error
+
+// sessionConfig holds the settings used to build the HTTP client for a session.
+type sessionConfig struct {
+	client  *http.Client
+	rootCAs *x509.CertPool
+	pinned  []byte
+	timeout time.Duration
+	proxy   *url.URL
+	vsys    string
+}
+
+// apiResponse contains the result of a request to the XML API.
+type apiResponse struct {
+	Body   []byte
+	Status int
+	Error  error
+}
+
+// defaultClient is used when a session was not created with NewSession, i.e. a PaloAlto{} literal.
+var defaultClient = newHTTPClient(&sessionConfig{})
+
+// WithHTTPClient uses the given HTTP client for all API requests. When a client is given, the TLS, proxy
+// and timeout options are ignored, and the client's own settings are used instead.
+func WithHTTPClient(client *http.Client) SessionOption {
+	return func(c *sessionConfig) error {
+		if client == nil {
+			return errors.New("you must specify a non-nil HTTP client")
+		}
+
+		c.client = client
+
+		return nil
+	}
+}
+
+// WithCACert verifies the device's certificate against the given PEM encoded CA bundle. By default, the
+// device's certificate is not verified.
+func WithCACert(pem []byte) SessionOption {
+	return func(c *sessionConfig) error {
+		if c.rootCAs == nil {
+			c.rootCAs = x509.NewCertPool()
+		}
+
+		if !c.rootCAs.AppendCertsFromPEM(pem) {
+			return errors.New("no valid certificates found in the CA bundle")
+		}
+
+		return nil
+	}
+}
+
+// WithPinnedCert will only trust a device that presents a certificate matching the given SHA-256 fingerprint.
+// The fingerprint is written in hex, and can be separated by colons, i.e. "AB:CD:EF:...".
+func WithPinnedCert(fingerprint string) SessionOption {
+	return func(c *sessionConfig) error {
+		fp, err := hex.DecodeString(strings.Replace(fingerprint, ":", "", -1))
+		if err != nil {
+			return fmt.Errorf("invalid certificate fingerprint: %s", err)
+		}
+
+		if len(fp) != sha256.Size {
+			return errors.New("the certificate fingerprint must be a SHA-256 hash")
+		}
+
+		c.pinned = fp
+
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit for each API request, which includes reading the response from the device.
+func WithTimeout(timeout time.Duration) SessionOption {
+	return func(c *sessionConfig) error {
+		c.timeout = timeout
+
+		return nil
+	}
+}
+
+// WithProxy sends all API requests through the given proxy, i.e. "http://proxy.company.com:8080".
+func WithProxy(proxy string) SessionOption {
+	return func(c *sessionConfig) error {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %s", err)
+		}
+
+		c.proxy = u
+
+		return nil
+	}
+}
+
+// WithVsys sets the vsys used on a multi-vsys firewall, i.e. "vsys2". It applies to every call made with the session,
+// unless a different vsys is given as the last parameter to the call. By default, vsys1 is used.
+func WithVsys(vsys string) SessionOption {
+	return func(c *sessionConfig) error {
+		if vsys == "" {
+			return errors.New("you must specify the name of the vsys")
+		}
+
+		c.vsys = vsys
+
+		return nil
+	}
+}
+
+// newHTTPClient builds the HTTP client for a session from the given settings.
+func newHTTPClient(c *sessionConfig) *http.Client {
+	if c.client != nil {
+		return c.client
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+	}
+
+	if c.rootCAs != nil {
+		tlsConfig.InsecureSkipVerify = false
+		tlsConfig.RootCAs = c.rootCAs
+	}
+
+	if c.pinned != nil {
+		pinned := c.pinned
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("the device did not present a certificate")
+			}
+
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], pinned) {
+				return errors.New("the device's certificate does not match the pinned fingerprint")
+			}
+
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	if c.proxy != nil {
+		transport.Proxy = http.ProxyURL(c.proxy)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.timeout,
+	}
+}
+
+// send issues a request to the XML API. The query parameters are sent in the URL for "get" requests, and
+// as a form-encoded body for "post" requests, which is better suited for large elements.
+func (p *PaloAlto) send(ctx context.Context, method string, query map[string]string) *apiResponse {
+	var req *http.Request
+	var err error
+	client := p.client
+	uri := strings.TrimSuffix(p.URI, "?")
+	values := url.Values{}
+
+	if client == nil {
+		client = defaultClient
+	}
+
+	for k, v := range query {
+		values.Set(k, v)
+	}
+
+	switch strings.ToLower(method) {
+	case "post":
+		req, err = http.NewRequest("POST", uri, strings.NewReader(values.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	default:
+		req, err = http.NewRequest("GET", fmt.Sprintf("%s?%s", uri, values.Encode()), nil)
+	}
+
+	if err != nil {
+		return &apiResponse{Error: err}
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return &apiResponse{Error: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &apiResponse{Status: resp.StatusCode, Error: err}
+	}
+
+	return &apiResponse{Body: body, Status: resp.StatusCode}
+}
+
+// upload sends the contents of the given reader as a multipart file upload to the XML API, such as when importing a
+// configuration file. The query parameters are sent in the URL.
+func (p *PaloAlto) upload(ctx context.Context, query map[string]string, filename string, file io.Reader) *apiResponse {
+	var b bytes.Buffer
+	client := p.client
+	uri := strings.TrimSuffix(p.URI, "?")
+	values := url.Values{}
+	mwriter := multipart.NewWriter(&b)
+
+	if client == nil {
+		client = defaultClient
+	}
+
+	for k, v := range query {
+		values.Set(k, v)
+	}
+
+	fw, err := mwriter.CreateFormFile("file", filename)
+	if err != nil {
+		return &apiResponse{Error: err}
+	}
+
+	if _, err := io.Copy(fw, file); err != nil {
+		return &apiResponse{Error: err}
+	}
+
+	mwriter.Close()
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s?%s", uri, values.Encode()), &b)
+	if err != nil {
+		return &apiResponse{Error: err}
+	}
+
+	req.Header.Set("Content-Type", mwriter.FormDataContentType())
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return &apiResponse{Error: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &apiResponse{Status: resp.StatusCode, Error: err}
+	}
+
+	return &apiResponse{Body: body, Status: resp.StatusCode}
+}
@@ -0,0 +1,227 @@
+package panos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// success is the response to a request that changed the configuration, or returned nothing.
+const success = `<response status="success"><result/></response>`
+
+// fakeDevice answers XML API requests with the response returned by respond, and records each request it receives.
+type fakeDevice struct {
+	server   *httptest.Server
+	respond  func(q url.Values) string
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+// newFakeDevice starts a fake device, and returns a firewall session connected to it. The session can be changed
+// (i.e. to a Panorama device) before it is used.
+func newFakeDevice(t *testing.T, respond func(q url.Values) string) (*PaloAlto, *fakeDevice) {
+	t.Helper()
+
+	d := &fakeDevice{respond: respond}
+	d.server = httptest.NewTLSServer(http.HandlerFunc(d.serve))
+	t.Cleanup(d.server.Close)
+
+	p := &PaloAlto{
+		Host:            strings.TrimPrefix(d.server.URL, "https://"),
+		Key:             "secret",
+		URI:             d.server.URL + "/api/?",
+		SoftwareVersion: "10.1.0",
+		DeviceType:      "panos",
+		client:          d.server.Client(),
+	}
+
+	return p, d
+}
+
+// serve records the request, and writes the response.
+func (d *fakeDevice) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d.mu.Lock()
+	d.requests = append(d.requests, r.Form)
+	d.mu.Unlock()
+
+	body := success
+	if d.respond != nil {
+		body = d.respond(r.Form)
+	}
+
+	w.Write([]byte(body))
+}
+
+// sent returns the requests received so far, optionally only those with the given action (or type, for requests
+// without an action).
+func (d *fakeDevice) sent(action string) []url.Values {
+	var reqs []url.Values
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, q := range d.requests {
+		if action == "" || q.Get("action") == action || (q.Get("action") == "" && q.Get("type") == action) {
+			reqs = append(reqs, q)
+		}
+	}
+
+	return reqs
+}
+
+func TestNewSession(t *testing.T) {
+	_, d := newFakeDevice(t, func(q url.Values) string {
+		switch {
+		case q.Get("type") == "keygen":
+			return `<response status="success"><result><key>abc123</key></result></response>`
+		case strings.Contains(q.Get("cmd"), "<system><info>"):
+			return `<response status="success"><result><system><platform-family>vm</platform-family><model>PA-VM</model>` +
+				`<serial>0123</serial><sw-version>10.1.6</sw-version></system></result></response>`
+		case strings.Contains(q.Get("cmd"), "panorama-status"):
+			return `<response status="success"><result>Panorama Server 1 : 10.0.0.5&#10;    Connected     : yes</result></response>`
+		}
+
+		return success
+	})
+
+	host := strings.TrimPrefix(d.server.URL, "https://")
+
+	p, err := NewSession(host, "admin", "paloalto", WithHTTPClient(d.server.Client()), WithVsys("vsys2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Key != "abc123" || p.Model != "PA-VM" || p.SoftwareVersion != "10.1.6" || p.DeviceType != "panos" || !p.Panorama {
+		t.Errorf("unexpected session: %+v", p)
+	}
+
+	if p.vsys != "vsys2" {
+		t.Errorf("vsys = %q, want vsys2", p.vsys)
+	}
+
+	keygen := d.sent("keygen")
+	if len(keygen) != 1 || keygen[0].Get("user") != "admin" || keygen[0].Get("password") != "paloalto" {
+		t.Errorf("unexpected keygen request: %v", keygen)
+	}
+}
+
+func TestNewSessionInvalidKey(t *testing.T) {
+	_, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="error" code="403"><result><msg>Invalid Credential</msg></result></response>`
+	})
+
+	_, err := NewSession(strings.TrimPrefix(d.server.URL, "https://"), "admin", "wrong", WithHTTPClient(d.server.Client()))
+	if !IsUnauthorized(err) {
+		t.Fatalf("err = %v, want an unauthorized error", err)
+	}
+}
+
+func TestSessionOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		option SessionOption
+		ok     bool
+	}{
+		{"nil client", WithHTTPClient(nil), false},
+		{"invalid CA", WithCACert([]byte("not a certificate")), false},
+		{"invalid fingerprint", WithPinnedCert("zz"), false},
+		{"short fingerprint", WithPinnedCert("AB:CD"), false},
+		{"fingerprint", WithPinnedCert(strings.Repeat("AB:", 31) + "AB"), true},
+		{"empty vsys", WithVsys(""), false},
+		{"timeout", WithTimeout(time.Second), true},
+		{"proxy", WithProxy("http://proxy.company.com:8080"), true},
+	}
+
+	for _, tt := range tests {
+		var c sessionConfig
+
+		if err := tt.option(&c); (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
+
+func TestSendMethods(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	p.send(context.Background(), "get", map[string]string{"type": "config", "action": "get", "xpath": "/config"})
+	p.send(context.Background(), "post", map[string]string{"type": "config", "action": "set", "element": "<a>&</a>"})
+
+	if got := d.sent("get"); len(got) != 1 || got[0].Get("xpath") != "/config" {
+		t.Errorf("unexpected get request: %v", got)
+	}
+
+	if got := d.sent("set"); len(got) != 1 || got[0].Get("element") != "<a>&</a>" {
+		t.Errorf("unexpected set request: %v", got)
+	}
+}
+
+func TestSendCanceled(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if resp := p.send(ctx, "get", map[string]string{"type": "op"}); resp.Error == nil {
+		t.Error("expected an error for a canceled context")
+	}
+
+	if len(d.sent("")) != 0 {
+		t.Error("a request was sent with a canceled context")
+	}
+}
+
+func TestSessionVsysXpath(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+	p.vsys = "vsys2"
+
+	if err := p.CreateTag("prod", "Red", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.CreateTag("lab", "", "", VsysLocation("vsys3")); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := d.sent("set")
+	if !strings.HasSuffix(sent[0].Get("xpath"), "/vsys/entry[@name='vsys2']/tag/entry[@name='prod']") || !strings.HasSuffix(sent[1].Get("xpath"), "/vsys/entry[@name='vsys3']/tag/entry[@name='lab']") {
+		t.Errorf("unexpected requests %v", sent)
+	}
+}
+
+func TestVsys(t *testing.T) {
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result><vsys><entry name="vsys1"><display-name>Internet</display-name></entry>` +
+			`<entry name="vsys2"/></vsys></result></response>`
+	})
+
+	vsys, err := p.Vsys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []VirtualSystem{{Name: "vsys1", DisplayName: "Internet"}, {Name: "vsys2"}}
+	if !reflect.DeepEqual(vsys.Vsys, want) {
+		t.Errorf("got %+v", vsys.Vsys)
+	}
+
+	if xpath := d.sent("get")[0].Get("xpath"); xpath != "/config/devices/entry[@name='localhost.localdomain']/vsys" {
+		t.Errorf("got xpath %s", xpath)
+	}
+
+	p.DeviceType = "panorama"
+	if _, err := p.Vsys(); err == nil {
+		t.Error("expected an error on Panorama")
+	}
+}
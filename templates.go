@@ -1,12 +1,11 @@
 package panos
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"strings"
-
-	"github.com/scottdware/go-rested"
 )
 
 // Templates lists all of the templates in Panorama.
@@ -43,10 +42,14 @@ type TemplateStack struct {
 // Templates returns information about all of the templates in Panorama, and what devices they are
 // applied to.
 func (p *PaloAlto) Templates() (*Templates, error) {
+	return p.TemplatesContext(context.Background())
+}
+
+// TemplatesContext is the same as Templates, but uses the given context for all API requests.
+func (p *PaloAlto) TemplatesContext(ctx context.Context) (*Templates, error) {
 	var temps Templates
 	xpath := "/config/devices/entry//template"
 	// xpath := "/config/devices/entry/vsys/entry/address"
-	r := rested.NewRequest()
 
 	if p.DeviceType != "panorama" {
 		return nil, errors.New("templates can only be listed on a Panorama device")
@@ -59,14 +62,17 @@ func (p *PaloAlto) Templates() (*Templates, error) {
 		"key":    p.Key,
 	}
 
-	tData := r.Send("get", p.URI, nil, headers, query)
+	tData := p.send(ctx, "get", query)
+	if tData.Error != nil {
+		return nil, tData.Error
+	}
 
 	if err := xml.Unmarshal(tData.Body, &temps); err != nil {
 		return nil, err
 	}
 
 	if temps.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", temps.Code, errorCodes[temps.Code])
+		return nil, newAPIError(tData, query)
 	}
 
 	return &temps, nil
@@ -75,11 +81,15 @@ func (p *PaloAlto) Templates() (*Templates, error) {
 // TemplateStacks returns information about all of the template stacks in Panorama, and what templates, devices
 // are assigned to them. This is ONLY available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) TemplateStacks() (*TemplateStacks, error) {
+	return p.TemplateStacksContext(context.Background())
+}
+
+// TemplateStacksContext is the same as TemplateStacks, but uses the given context for all API requests.
+func (p *PaloAlto) TemplateStacksContext(ctx context.Context) (*TemplateStacks, error) {
 	var temps TemplateStacks
 	ver := splitSWVersion(p.SoftwareVersion)
 	xpath := "/config/devices/entry//template-stack"
 	// xpath := "/config/devices/entry/vsys/entry/address"
-	r := rested.NewRequest()
 
 	if p.DeviceType != "panorama" {
 		return nil, errors.New("template stacks can only be listed on a Panorama device")
@@ -96,14 +106,17 @@ func (p *PaloAlto) TemplateStacks() (*TemplateStacks, error) {
 		"key":    p.Key,
 	}
 
-	tData := r.Send("get", p.URI, nil, headers, query)
+	tData := p.send(ctx, "get", query)
+	if tData.Error != nil {
+		return nil, tData.Error
+	}
 
 	if err := xml.Unmarshal(tData.Body, &temps); err != nil {
 		return nil, err
 	}
 
 	if temps.Status != "success" {
-		return nil, fmt.Errorf("error code %s: %s", temps.Code, errorCodes[temps.Code])
+		return nil, newAPIError(tData, query)
 	}
 
 	return &temps, nil
@@ -112,23 +125,31 @@ func (p *PaloAlto) TemplateStacks() (*TemplateStacks, error) {
 // CreateTemplate adds a new template to Panorama. If you wish to associate devices, then
 // separate them with a comma, i.e.: "0101010101, 0202020202".
 func (p *PaloAlto) CreateTemplate(name, description string, devices ...string) error {
+	return p.CreateTemplateContext(context.Background(), name, description, devices...)
+}
+
+// CreateTemplateContext is the same as CreateTemplate, but uses the given context for all API requests.
+func (p *PaloAlto) CreateTemplateContext(ctx context.Context, name, description string, devices ...string) error {
 	var reqError requestError
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]", xpathLiteral(name))
 	xmlBody := "<settings><default-vsys>vsys1</default-vsys></settings><config><devices><entry name=\"localhost.localdomain\"><vsys><entry name=\"vsys1\"/></vsys></entry></devices></config>"
-	r := rested.NewRequest()
+
+	if err := validateName(name); err != nil {
+		return err
+	}
 
 	if p.DeviceType != "panorama" {
 		return errors.New("templates can only be created on a Panorama device")
 	}
 
 	if len(description) > 0 {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
 	if len(devices) > 0 {
 		xmlBody += "<devices>"
 		for _, d := range strings.Split(devices[0], ",") {
-			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(d))
+			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", escapeXML(strings.TrimSpace(d)))
 		}
 		xmlBody += "</devices>"
 	}
@@ -141,7 +162,7 @@ func (p *PaloAlto) CreateTemplate(name, description string, devices ...string) e
 		"element": xmlBody,
 	}
 
-	resp := r.Send("get", p.URI, nil, headers, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -151,7 +172,7 @@ func (p *PaloAlto) CreateTemplate(name, description string, devices ...string) e
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -161,15 +182,23 @@ func (p *PaloAlto) CreateTemplate(name, description string, devices ...string) e
 // If you wish to associate devices, then separate them with a comma, just like you would template names.
 // This is ONLY available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devices ...string) error {
+	return p.CreateTemplateStackContext(context.Background(), name, description, templates, devices...)
+}
+
+// CreateTemplateStackContext is the same as CreateTemplateStack, but uses the given context for all API requests.
+func (p *PaloAlto) CreateTemplateStackContext(ctx context.Context, name, description, templates string, devices ...string) error {
 	var reqError requestError
 	ver := splitSWVersion(p.SoftwareVersion)
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]", xpathLiteral(name))
 	xmlBody := "<templates>"
 	for _, t := range strings.Split(templates, ",") {
-		xmlBody += fmt.Sprintf("<member>%s</member>", strings.TrimSpace(t))
+		xmlBody += fmt.Sprintf("<member>%s</member>", escapeXML(strings.TrimSpace(t)))
 	}
 	xmlBody += "</templates>"
-	r := rested.NewRequest()
+
+	if err := validateName(name); err != nil {
+		return err
+	}
 
 	if p.DeviceType != "panorama" {
 		return errors.New("template stacks can only be created on a Panorama device")
@@ -180,13 +209,13 @@ func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devi
 	}
 
 	if len(description) > 0 {
-		xmlBody += fmt.Sprintf("<description>%s</description>", description)
+		xmlBody += fmt.Sprintf("<description>%s</description>", escapeXML(description))
 	}
 
 	if len(devices) > 0 {
 		xmlBody += "<devices>"
 		for _, d := range strings.Split(devices[0], ",") {
-			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(d))
+			xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", escapeXML(strings.TrimSpace(d)))
 		}
 		xmlBody += "</devices>"
 	}
@@ -199,7 +228,7 @@ func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devi
 		"element": xmlBody,
 	}
 
-	resp := r.Send("get", p.URI, nil, headers, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -209,7 +238,7 @@ func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devi
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -221,18 +250,22 @@ func (p *PaloAlto) CreateTemplateStack(name, description, templates string, devi
 // will only assign devices to a single template. Template stacks are ONLY
 // available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) AssignTemplate(name, devices string, stack bool) error {
+	return p.AssignTemplateContext(context.Background(), name, devices, stack)
+}
+
+// AssignTemplateContext is the same as AssignTemplate, but uses the given context for all API requests.
+func (p *PaloAlto) AssignTemplateContext(ctx context.Context, name, devices string, stack bool) error {
 	var reqError requestError
 	ver := splitSWVersion(p.SoftwareVersion)
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']", name)
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]", xpathLiteral(name))
 	xmlBody := "<devices>"
 	for _, d := range strings.Split(devices, ",") {
-		xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", strings.TrimSpace(d))
+		xmlBody += fmt.Sprintf("<entry name=\"%s\"/>", escapeXML(strings.TrimSpace(d)))
 	}
 	xmlBody += "</devices>"
-	r := rested.NewRequest()
 
 	if stack {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='%s']", name)
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]", xpathLiteral(name))
 	}
 
 	if p.DeviceType != "panorama" {
@@ -251,7 +284,7 @@ func (p *PaloAlto) AssignTemplate(name, devices string, stack bool) error {
 		"element": xmlBody,
 	}
 
-	resp := r.Send("get", p.URI, nil, nil, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -261,7 +294,7 @@ func (p *PaloAlto) AssignTemplate(name, devices string, stack bool) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -272,13 +305,17 @@ func (p *PaloAlto) AssignTemplate(name, devices string, stack bool) error {
 // will only delete single templates. Template stacks are ONLY
 // available on Panorama version 7.0.0 and higher.
 func (p *PaloAlto) DeleteTemplate(name string, stack bool) error {
+	return p.DeleteTemplateContext(context.Background(), name, stack)
+}
+
+// DeleteTemplateContext is the same as DeleteTemplate, but uses the given context for all API requests.
+func (p *PaloAlto) DeleteTemplateContext(ctx context.Context, name string, stack bool) error {
 	var reqError requestError
 	ver := splitSWVersion(p.SoftwareVersion)
-	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name='%s']", name)
-	r := rested.NewRequest()
+	xpath := fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template/entry[@name=%s]", xpathLiteral(name))
 
 	if stack {
-		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name='%s']", name)
+		xpath = fmt.Sprintf("/config/devices/entry[@name='localhost.localdomain']/template-stack/entry[@name=%s]", xpathLiteral(name))
 	}
 
 	if p.DeviceType != "panorama" {
@@ -296,7 +333,7 @@ func (p *PaloAlto) DeleteTemplate(name string, stack bool) error {
 		"key":    p.Key,
 	}
 
-	resp := r.Send("get", p.URI, nil, headers, query)
+	resp := p.send(ctx, "get", query)
 	if resp.Error != nil {
 		return resp.Error
 	}
@@ -306,7 +343,7 @@ func (p *PaloAlto) DeleteTemplate(name string, stack bool) error {
 	}
 
 	if reqError.Status != "success" {
-		return fmt.Errorf("error code %s: %s", reqError.Code, errorCodes[reqError.Code])
+		return newAPIError(resp, query)
 	}
 
 	return nil
@@ -0,0 +1,427 @@
+package panos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ObjectReference describes an address group, service group or rule that refers to an object.
+type ObjectReference struct {
+	// Location is where the group or rule that refers to the object lives.
+	Location Location
+	// ObjectType is the type of the group or rule, and is one of: address-group, service-group, security-rule,
+	// nat-rule, pbf-rule, decryption-rule or qos-rule.
+	ObjectType string
+	// Name is the name of the group or rule.
+	Name string
+	// Rulebase is the rulebase of the rule on Panorama (pre or post), and is empty otherwise.
+	Rulebase string
+	// Field is the part of the group or rule that refers to the object, i.e. "members", "dynamic-filter",
+	// "source" or "destination-translation".
+	Field string
+	// XPath is the xpath of the group or rule.
+	XPath string
+	// ObjectLocation is where the object that is referred to lives, which is either the same location as the group
+	// or rule, or shared.
+	ObjectLocation Location
+}
+
+// usageTypes contains the object types that groups and rules can refer to.
+var usageTypes = []string{"address", "address-group", "service", "service-group", "tag", "url-category"}
+
+// objectUse contains a reference to an object, along with the name of the object it refers to, the types of object
+// it can be (in the order they are looked for), and the location it is made from.
+type objectUse struct {
+	object string
+	types  []string
+	from   Location
+	base   string
+	ref    ObjectReference
+}
+
+// objectKey identifies an object by the xpath of its location, its type and its name.
+type objectKey struct {
+	location   string
+	objecttype string
+	name       string
+}
+
+// objectIndex contains every object that groups and rules can refer to, in each of the locations it was built for.
+type objectIndex map[objectKey]bool
+
+// xmlRuleReferences is used for parsing the rules of the rulebases that don't have their own type, such as policy
+// based forwarding, decryption and QoS.
+type xmlRuleReferences struct {
+	Rules []xmlRuleReference `xml:"entry"`
+}
+
+// xmlRuleReference is used for parsing the fields of a rule that can refer to objects.
+type xmlRuleReference struct {
+	Name        string   `xml:"name,attr"`
+	Source      []string `xml:"source>member"`
+	Destination []string `xml:"destination>member"`
+	Service     []string `xml:"service>member"`
+	Category    []string `xml:"category>member"`
+	Tags        []string `xml:"tag>member"`
+}
+
+// WhereUsed searches the address groups, service groups, dynamic group filters, and the security, NAT, policy based
+// forwarding, decryption and QoS rules for references to the object of the given type and name, and returns each of
+// them. objecttype should be one of:
+// address, address-group, service, service-group, tag or url-category. An object is referenced by a dynamic group
+// filter when it is a tag used in the filter. References are resolved the same way as PAN-OS: a group or rule refers
+// to the object of that name in its own device-group (or vsys) if there is one, and otherwise to the shared object.
+// Parent device-groups are not taken into account. You can (optionally) specify the location of the object as the
+// last parameter, such as a DeviceGroupLocation or SharedLocation. Otherwise, references to an object of that name
+// in shared and every device-group (or every vsys on a firewall) are returned, and ObjectLocation says which one each
+// of them refers to. Use this to find out what is stopping an object from being deleted.
+func (p *PaloAlto) WhereUsed(objecttype, name string, loc ...Location) ([]ObjectReference, error) {
+	return p.WhereUsedContext(context.Background(), objecttype, name, loc...)
+}
+
+// WhereUsedContext is the same as WhereUsed, but uses the given context for all API requests.
+func (p *PaloAlto) WhereUsedContext(ctx context.Context, objecttype, name string, loc ...Location) ([]ObjectReference, error) {
+	var refs []ObjectReference
+	var target string
+
+	if name == "" {
+		return nil, errors.New("you must specify the name of the object")
+	}
+
+	if !isUsageType(objecttype) {
+		return nil, fmt.Errorf("unknown object type %s, should be one of: address, address-group, service, service-group, tag or url-category", objecttype)
+	}
+
+	locs, err := p.usageLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := locs
+
+	if len(loc) > 0 {
+		l, err := p.location(loc)
+		if err != nil {
+			return nil, err
+		}
+
+		if l.Template != "" || l.TemplateStack != "" {
+			return nil, errors.New("the location of the object should be shared, a device-group or a vsys")
+		}
+
+		if target, err = l.Xpath(); err != nil {
+			return nil, err
+		}
+
+		// An object outside of shared can only be referred to from its own location.
+		if !l.Shared {
+			scan = []Location{l}
+			locs = []Location{SharedLocation(), l}
+		}
+	}
+
+	idx, err := p.objectIndex(ctx, locs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range scan {
+		uses, err := p.locationUses(ctx, l)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, u := range uses {
+			key, objLoc, ok := idx.resolve(u)
+			if !ok || key.objecttype != objecttype || key.name != name || (target != "" && key.location != target) {
+				continue
+			}
+
+			ref := u.ref
+			ref.ObjectLocation = objLoc
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs, nil
+}
+
+// isUsageType returns true if groups and rules can refer to objects of the given type.
+func isUsageType(objecttype string) bool {
+	for _, t := range usageTypes {
+		if t == objecttype {
+			return true
+		}
+	}
+
+	return false
+}
+
+// objectIndex retrieves the names of every object that groups and rules can refer to in the given locations.
+func (p *PaloAlto) objectIndex(ctx context.Context, locs []Location) (objectIndex, error) {
+	idx := objectIndex{}
+
+	for _, l := range locs {
+		base, err := l.Xpath()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, objecttype := range usageTypes {
+			var names xmlEntryNames
+
+			data, err := p.ConfigGetContext(ctx, fmt.Sprintf("%s/%s", base, planPaths[objecttype]))
+			if err != nil && !IsNotFound(err) {
+				return nil, err
+			}
+
+			if len(data) > 0 {
+				if err := xml.Unmarshal(data, &names); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, e := range names.Entries {
+				idx[objectKey{location: base, objecttype: objecttype, name: e.Name}] = true
+			}
+		}
+	}
+
+	return idx, nil
+}
+
+// resolve returns the object that a reference refers to, along with its location. The location the reference is made
+// from is looked in first, followed by shared, and within each location the types are looked for in order. It returns
+// false if there is no such object, such as when a rule refers to a predefined service or an application.
+func (idx objectIndex) resolve(u objectUse) (objectKey, Location, bool) {
+	for _, l := range []Location{u.from, SharedLocation()} {
+		base := u.base
+		if l.Shared {
+			base = "/config/shared"
+		}
+
+		for _, objecttype := range u.types {
+			key := objectKey{location: base, objecttype: objecttype, name: u.object}
+			if idx[key] {
+				return key, l, true
+			}
+		}
+	}
+
+	return objectKey{}, Location{}, false
+}
+
+// usageLocations returns shared, followed by every device-group on Panorama, or every vsys on a firewall.
+func (p *PaloAlto) usageLocations(ctx context.Context) ([]Location, error) {
+	locs := []Location{SharedLocation()}
+
+	if p.DeviceType == "panorama" {
+		dgs, err := p.DeviceGroupsContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dg := range dgs.Groups {
+			locs = append(locs, DeviceGroupLocation(dg.Name))
+		}
+
+		return locs, nil
+	}
+
+	vsys, err := p.VsysContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range vsys.Vsys {
+		locs = append(locs, VsysLocation(v.Name))
+	}
+
+	return locs, nil
+}
+
+// locationUses returns every reference to an object made by the address groups, service groups and rules in the
+// given location.
+func (p *PaloAlto) locationUses(ctx context.Context, l Location) ([]objectUse, error) {
+	var uses []objectUse
+	loc := []Location{l}
+
+	base, err := l.Xpath()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{"address", "address-group"}
+	services := []string{"service", "service-group"}
+	tags := []string{"tag"}
+	categories := []string{"url-category"}
+
+	add := func(ref ObjectReference, field string, types []string, objects ...string) {
+		ref.Field = field
+
+		for _, o := range objects {
+			if o != "" && o != "any" {
+				uses = append(uses, objectUse{object: o, types: types, from: l, base: base, ref: ref})
+			}
+		}
+	}
+
+	addrGroups, err := p.AddressGroupsContext(ctx, loc...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range addrGroups.Groups {
+		ref := ObjectReference{Location: l, ObjectType: "address-group", Name: g.Name, XPath: fmt.Sprintf("%s/address-group/entry[@name=%s]", base, xpathLiteral(g.Name))}
+
+		add(ref, "members", addresses, g.Members...)
+		add(ref, "dynamic-filter", tags, filterTags(g.DynamicFilter)...)
+	}
+
+	svcGroups, err := p.ServiceGroupsContext(ctx, loc...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range svcGroups.Groups {
+		ref := ObjectReference{Location: l, ObjectType: "service-group", Name: g.Name, XPath: fmt.Sprintf("%s/service-group/entry[@name=%s]", base, xpathLiteral(g.Name))}
+
+		add(ref, "members", services, g.Members...)
+	}
+
+	// Rules can't be shared on a firewall, and there is only a single rulebase.
+	rulebases := []string{"pre", "post"}
+	if p.DeviceType == "panos" {
+		if l.Shared {
+			return uses, nil
+		}
+
+		rulebases = []string{""}
+	}
+
+	for _, rulebase := range rulebases {
+		secXpath, err := p.rulebaseXpath("security", rulebase, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		natXpath, err := p.rulebaseXpath("nat", rulebase, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		secRules, err := p.SecurityRulesContext(ctx, rulebase, loc...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range secRules.Rules {
+			ref := ObjectReference{Location: l, ObjectType: "security-rule", Name: r.Name, Rulebase: rulebase, XPath: fmt.Sprintf("%s/entry[@name=%s]", secXpath, xpathLiteral(r.Name))}
+
+			add(ref, "source", addresses, r.Source...)
+			add(ref, "destination", addresses, r.Destination...)
+			add(ref, "service", services, r.Service...)
+			add(ref, "category", categories, r.Category...)
+			add(ref, "tag", tags, r.Tags...)
+		}
+
+		natRules, err := p.NATRulesContext(ctx, rulebase, loc...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range natRules.Rules {
+			ref := ObjectReference{Location: l, ObjectType: "nat-rule", Name: r.Name, Rulebase: rulebase, XPath: fmt.Sprintf("%s/entry[@name=%s]", natXpath, xpathLiteral(r.Name))}
+
+			add(ref, "source", addresses, r.Source...)
+			add(ref, "destination", addresses, r.Destination...)
+			add(ref, "service", services, r.Service)
+			add(ref, "source-translation", addresses, r.SourceTranslatedAddresses...)
+			add(ref, "destination-translation", addresses, r.DestinationTranslatedAddress)
+			add(ref, "tag", tags, r.Tags...)
+		}
+
+		for _, ruletype := range []string{"pbf", "decryption", "qos"} {
+			var rules xmlRuleReferences
+
+			xpath, err := p.rulebaseXpath(ruletype, rulebase, loc)
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := p.ConfigGetContext(ctx, xpath)
+			if err != nil && !IsNotFound(err) {
+				return nil, err
+			}
+
+			if len(data) > 0 {
+				if err := xml.Unmarshal(data, &rules); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, r := range rules.Rules {
+				ref := ObjectReference{Location: l, ObjectType: ruletype + "-rule", Name: r.Name, Rulebase: rulebase, XPath: fmt.Sprintf("%s/entry[@name=%s]", xpath, xpathLiteral(r.Name))}
+
+				add(ref, "source", addresses, r.Source...)
+				add(ref, "destination", addresses, r.Destination...)
+				add(ref, "service", services, r.Service...)
+				add(ref, "category", categories, r.Category...)
+				add(ref, "tag", tags, r.Tags...)
+			}
+		}
+	}
+
+	return uses, nil
+}
+
+// filterTags returns the tags used in a dynamic address group filter, i.e. "'web servers' and (prod or dmz)" uses the
+// tags: web servers, prod and dmz.
+func filterTags(filter string) []string {
+	var tags []string
+	var word strings.Builder
+	var quote rune
+
+	// Quoted tags are kept even if they look like an operator.
+	flush := func(quoted bool) {
+		w := word.String()
+		word.Reset()
+
+		switch strings.ToLower(w) {
+		case "and", "or", "not":
+			if quoted {
+				break
+			}
+
+			fallthrough
+		case "":
+			return
+		}
+
+		tags = append(tags, w)
+	}
+
+	for _, c := range filter {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+			flush(true)
+		case quote != 0:
+			word.WriteRune(c)
+		case c == '\'' || c == '"':
+			flush(false)
+			quote = c
+		case c == ' ' || c == '(' || c == ')':
+			flush(false)
+		default:
+			word.WriteRune(c)
+		}
+	}
+
+	flush(false)
+
+	return tags
+}
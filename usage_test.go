@@ -0,0 +1,161 @@
+package panos
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFakePanorama returns a session for a fake Panorama device with the device-groups dg1 and dg2. Config contains
+// the entries of each part of the configuration by the end of its xpath, i.e. "shared/address" or
+// "'dg1']/pre-rulebase/security/rules", and everything else is empty.
+func newFakePanorama(t *testing.T, config map[string]string) (*PaloAlto, *fakeDevice) {
+	config["/device-group"] = `<entry name="dg1"/><entry name="dg2"/>`
+
+	p, d := newFakeDevice(t, func(q url.Values) string {
+		xpath := q.Get("xpath")
+		node := xpath[strings.LastIndex(xpath, "/")+1:]
+
+		for suffix, entries := range config {
+			if strings.HasSuffix(xpath, suffix) {
+				return `<response status="success"><result><` + node + `>` + entries + `</` + node + `></result></response>`
+			}
+		}
+
+		return `<response status="success"><result/></response>`
+	})
+	p.DeviceType = "panorama"
+
+	return p, d
+}
+
+// sharedAndShadowed is a configuration where dg1 has its own web address, which shadows the shared one, and dg2 uses
+// the shared one. There is also a service named web in dg1.
+var sharedAndShadowed = map[string]string{
+	"shared/address":                      `<entry name="web"><ip-netmask>10.1.1.1</ip-netmask></entry>`,
+	"shared/address-group":                `<entry name="shared-servers"><static><member>web</member></static></entry>`,
+	"'dg1']/address":                      `<entry name="web"><ip-netmask>10.2.2.2</ip-netmask></entry>`,
+	"'dg1']/service":                      `<entry name="web"><protocol><tcp><port>8080</port></tcp></protocol></entry>`,
+	"'dg1']/pre-rulebase/security/rules":  `<entry name="dg1-rule"><source><member>web</member></source><service><member>web</member></service></entry>`,
+	"'dg2']/post-rulebase/security/rules": `<entry name="dg2-rule"><destination><member>web</member></destination></entry>`,
+	"'dg2']/pre-rulebase/nat/rules":       `<entry name="dg2-nat"><destination-translation><translated-address>web</translated-address></destination-translation></entry>`,
+}
+
+func TestWhereUsedShared(t *testing.T) {
+	p, _ := newFakePanorama(t, sharedAndShadowed)
+
+	refs, err := p.WhereUsed("address", "web", SharedLocation())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range refs {
+		got = append(got, r.Location.String()+" "+r.ObjectType+" "+r.Name+" "+r.Field+" -> "+r.ObjectLocation.String())
+	}
+
+	want := []string{
+		"shared address-group shared-servers members -> shared",
+		"device-group dg2 nat-rule dg2-nat destination-translation -> shared",
+		"device-group dg2 security-rule dg2-rule destination -> shared",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestWhereUsedDeviceGroup(t *testing.T) {
+	p, d := newFakePanorama(t, sharedAndShadowed)
+
+	refs, err := p.WhereUsed("address", "web", DeviceGroupLocation("dg1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(refs) != 1 || refs[0].Name != "dg1-rule" || refs[0].Field != "source" || refs[0].ObjectLocation.DeviceGroup != "dg1" {
+		t.Errorf("got %+v", refs)
+	}
+
+	for _, q := range d.sent("get") {
+		if strings.Contains(q.Get("xpath"), "'dg2'") {
+			t.Errorf("dg2 should not be searched for a dg1 object: %s", q.Get("xpath"))
+		}
+	}
+
+	refs, err = p.WhereUsed("service", "web", DeviceGroupLocation("dg1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(refs) != 1 || refs[0].Field != "service" {
+		t.Errorf("got %+v", refs)
+	}
+}
+
+func TestWhereUsedAnyLocation(t *testing.T) {
+	p, _ := newFakePanorama(t, sharedAndShadowed)
+
+	refs, err := p.WhereUsed("address", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locations := map[string]int{}
+	for _, r := range refs {
+		locations[r.ObjectLocation.String()]++
+	}
+
+	if len(refs) != 4 || locations["shared"] != 3 || locations["device-group dg1"] != 1 {
+		t.Errorf("got %+v", refs)
+	}
+
+	if _, err := p.WhereUsed("zone", "trust"); err == nil {
+		t.Error("expected an error for an unknown object type")
+	}
+}
+
+func TestWhereUsedTags(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		xpath := q.Get("xpath")
+
+		switch {
+		case strings.HasSuffix(xpath, "/vsys"):
+			return `<response status="success"><result><vsys><entry name="vsys1"/></vsys></result></response>`
+		case strings.HasSuffix(xpath, "'vsys1']/tag"):
+			return `<response status="success"><result><tag><entry name="web servers"/></tag></result></response>`
+		case strings.HasSuffix(xpath, "'vsys1']/address-group"):
+			return `<response status="success"><result><address-group><entry name="dynamic"><dynamic>` +
+				`<filter>'web servers' and prod</filter></dynamic></entry></address-group></result></response>`
+		}
+
+		return `<response status="success"><result/></response>`
+	})
+
+	refs, err := p.WhereUsed("tag", "web servers", VsysLocation("vsys1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(refs) != 1 || refs[0].Field != "dynamic-filter" || refs[0].ObjectLocation.Vsys != "vsys1" {
+		t.Errorf("got %+v", refs)
+	}
+}
+
+func TestFilterTags(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"'web servers' and (prod or dmz)", []string{"web servers", "prod", "dmz"}},
+		{`"and" or not lab`, []string{"and", "lab"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := filterTags(tt.filter); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q", tt.filter, got)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package panos
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IPTagMapping contains an IP address, and the tags to register or unregister for it. Registered IP addresses
+// become members of any dynamic address group whose criteria matches the tags. Timeout is the number of seconds
+// before the tags expire (PAN-OS 9.0.0 and later), and 0 means they never expire. Timeout is ignored when
+// unregistering tags.
+type IPTagMapping struct {
+	IP      string
+	Tags    []string
+	Timeout int
+}
+
+// RegisteredIPs contains a slice of all IP addresses that have tags registered on the device.
+type RegisteredIPs struct {
+	IPs []RegisteredIP
+}
+
+// RegisteredIP contains information about each individual registered IP address, and its tags.
+type RegisteredIP struct {
+	IP         string
+	Tags       []string
+	Persistent bool
+	FromAgent  bool
+}
+
+// UserMapping contains a user-to-IP mapping to log in or out, i.e. User "example\\jdoe" at IP "10.1.1.10".
+// Timeout is the number of minutes before the mapping expires, and 0 uses the device's default timeout.
+// Timeout is ignored when logging out.
+type UserMapping struct {
+	User    string
+	IP      string
+	Timeout int
+}
+
+// UserGroup contains a group, and all of the users that are members of it. Name is usually the distinguished
+// name of the group, i.e. "cn=engineering,ou=groups,dc=example,dc=com".
+type UserGroup struct {
+	Name    string
+	Members []string
+}
+
+// UserIDFailure contains information about each individual entry that the device rejected in a User-ID update.
+// Type is the kind of update the entry was part of, and is one of: login, logout, groups, register or unregister.
+type UserIDFailure struct {
+	Type    string
+	Name    string
+	IP      string
+	Message string
+}
+
+// uidTagEntry is used for building each individual IP-to-tag mapping.
+type uidTagEntry struct {
+	XMLName xml.Name `xml:"entry"`
+	IP      string   `xml:"ip,attr"`
+	Tags    []uidTag `xml:"tag>member"`
+}
+
+// uidTag is used for building each individual tag, along with its timeout.
+type uidTag struct {
+	Timeout int    `xml:"timeout,attr,omitempty"`
+	Name    string `xml:",chardata"`
+}
+
+// uidUserEntry is used for building each individual user-to-IP mapping.
+type uidUserEntry struct {
+	XMLName xml.Name `xml:"entry"`
+	Name    string   `xml:"name,attr"`
+	IP      string   `xml:"ip,attr"`
+	Timeout int      `xml:"timeout,attr,omitempty"`
+}
+
+// uidGroupEntry is used for building each individual group and its members.
+type uidGroupEntry struct {
+	XMLName xml.Name         `xml:"entry"`
+	Name    string           `xml:"name,attr"`
+	Members []uidGroupMember `xml:"members>entry"`
+}
+
+// uidGroupMember is used for building each individual member of a group.
+type uidGroupMember struct {
+	Name string `xml:"name,attr"`
+}
+
+// xmlUIDResponse is used for parsing the response to a User-ID update, which lists any entries that failed.
+type xmlUIDResponse struct {
+	XMLName xml.Name      `xml:"response"`
+	Status  string        `xml:"status,attr"`
+	Result  xmlUIDPayload `xml:"result>uid-response>payload"`
+	Errors  xmlUIDPayload `xml:"msg>line>uid-response>payload"`
+}
+
+// xmlUIDPayload is used for parsing the payload of the response to a User-ID update.
+type xmlUIDPayload struct {
+	Sections []xmlUIDSection `xml:",any"`
+}
+
+// xmlUIDSection is used for parsing each type of update in the response, i.e. <login>.
+type xmlUIDSection struct {
+	XMLName xml.Name
+	Entries []xmlUIDFailure `xml:"entry"`
+}
+
+// xmlUIDFailure is used for parsing each individual entry that failed.
+type xmlUIDFailure struct {
+	Name    string `xml:"name,attr"`
+	IP      string `xml:"ip,attr"`
+	Message string `xml:"message,attr"`
+}
+
+// xmlRegisteredIPs is used for parsing all registered IP addresses.
+type xmlRegisteredIPs struct {
+	XMLName xml.Name          `xml:"response"`
+	Status  string            `xml:"status,attr"`
+	Code    string            `xml:"code,attr"`
+	IPs     []xmlRegisteredIP `xml:"result>entry"`
+}
+
+// xmlRegisteredIP is used for parsing each individual registered IP address.
+type xmlRegisteredIP struct {
+	IP         string   `xml:"ip,attr"`
+	FromAgent  string   `xml:"from_agent,attr"`
+	Persistent string   `xml:"persistent,attr"`
+	Tags       []string `xml:"tag>member"`
+}
+
+// userIDBatchSize is the largest number of entries sent in a single User-ID API request, and userIDMaxSize is
+// the largest size of its entries in bytes. Larger updates are split up into multiple requests.
+var (
+	userIDBatchSize = 500
+	userIDMaxSize   = 512 * 1024
+)
+
+// tagEntries converts the given mappings into entries for a User-ID message.
+func tagEntries(mappings []IPTagMapping, timeouts bool) ([]interface{}, error) {
+	var entries []interface{}
+
+	for _, m := range mappings {
+		if m.IP == "" {
+			return nil, errors.New("you must specify an IP address for each mapping")
+		}
+
+		if len(m.Tags) <= 0 {
+			return nil, fmt.Errorf("you must specify at least one tag for %s", m.IP)
+		}
+
+		if m.Timeout < 0 {
+			return nil, fmt.Errorf("the tag timeout for %s cannot be negative", m.IP)
+		}
+
+		entry := uidTagEntry{IP: m.IP}
+		for _, t := range m.Tags {
+			tag := uidTag{Name: t}
+			if timeouts {
+				tag.Timeout = m.Timeout
+			}
+
+			entry.Tags = append(entry.Tags, tag)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// userEntries converts the given mappings into entries for a User-ID message.
+func userEntries(mappings []UserMapping, timeouts bool) ([]interface{}, error) {
+	var entries []interface{}
+
+	for _, m := range mappings {
+		if m.User == "" || m.IP == "" {
+			return nil, errors.New("you must specify a user and IP address for each mapping")
+		}
+
+		if m.Timeout < 0 {
+			return nil, fmt.Errorf("the timeout for %s cannot be negative", m.User)
+		}
+
+		entry := uidUserEntry{Name: m.User, IP: m.IP}
+		if timeouts {
+			entry.Timeout = m.Timeout
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// RegisterIPTags registers the given tags for each IP address, adding them as members to any dynamic address
+// group that matches the tags. Large numbers of mappings are sent in batches. If the device rejects any of the
+// mappings, then a *UserIDError is returned listing each of them.
+func (p *PaloAlto) RegisterIPTags(mappings []IPTagMapping) error {
+	return p.RegisterIPTagsContext(context.Background(), mappings)
+}
+
+// RegisterIPTagsContext is the same as RegisterIPTags, but uses the given context for all API requests.
+func (p *PaloAlto) RegisterIPTagsContext(ctx context.Context, mappings []IPTagMapping) error {
+	entries, err := tagEntries(mappings, true)
+	if err != nil {
+		return err
+	}
+
+	return p.sendUIDEntries(ctx, "register", entries)
+}
+
+// UnregisterIPTags removes the given tags from each IP address, removing them from any dynamic address
+// group that no longer matches. Large numbers of mappings are sent in batches. If the device rejects any of the
+// mappings, then a *UserIDError is returned listing each of them.
+func (p *PaloAlto) UnregisterIPTags(mappings []IPTagMapping) error {
+	return p.UnregisterIPTagsContext(context.Background(), mappings)
+}
+
+// UnregisterIPTagsContext is the same as UnregisterIPTags, but uses the given context for all API requests.
+func (p *PaloAlto) UnregisterIPTagsContext(ctx context.Context, mappings []IPTagMapping) error {
+	entries, err := tagEntries(mappings, false)
+	if err != nil {
+		return err
+	}
+
+	return p.sendUIDEntries(ctx, "unregister", entries)
+}
+
+// UserIDLogin maps each user to the given IP address, so they can be matched by user and group based policies.
+// Large numbers of mappings are sent in batches. If the device rejects any of the mappings, then a *UserIDError
+// is returned listing each of them.
+func (p *PaloAlto) UserIDLogin(mappings []UserMapping) error {
+	return p.UserIDLoginContext(context.Background(), mappings)
+}
+
+// UserIDLoginContext is the same as UserIDLogin, but uses the given context for all API requests.
+func (p *PaloAlto) UserIDLoginContext(ctx context.Context, mappings []UserMapping) error {
+	entries, err := userEntries(mappings, true)
+	if err != nil {
+		return err
+	}
+
+	return p.sendUIDEntries(ctx, "login", entries)
+}
+
+// UserIDLogout removes the mapping of each user to the given IP address. Large numbers of mappings are sent in
+// batches. If the device rejects any of the mappings, then a *UserIDError is returned listing each of them.
+func (p *PaloAlto) UserIDLogout(mappings []UserMapping) error {
+	return p.UserIDLogoutContext(context.Background(), mappings)
+}
+
+// UserIDLogoutContext is the same as UserIDLogout, but uses the given context for all API requests.
+func (p *PaloAlto) UserIDLogoutContext(ctx context.Context, mappings []UserMapping) error {
+	entries, err := userEntries(mappings, false)
+	if err != nil {
+		return err
+	}
+
+	return p.sendUIDEntries(ctx, "logout", entries)
+}
+
+// UserIDGroups sets the members of each group, replacing any members the device already knows of for that group.
+// Large numbers of groups are sent in batches. If the device rejects any of the groups, then a *UserIDError is
+// returned listing each of them.
+func (p *PaloAlto) UserIDGroups(groups []UserGroup) error {
+	return p.UserIDGroupsContext(context.Background(), groups)
+}
+
+// UserIDGroupsContext is the same as UserIDGroups, but uses the given context for all API requests.
+func (p *PaloAlto) UserIDGroupsContext(ctx context.Context, groups []UserGroup) error {
+	var entries []interface{}
+
+	for _, g := range groups {
+		if g.Name == "" {
+			return errors.New("you must specify a name for each group")
+		}
+
+		entry := uidGroupEntry{Name: g.Name}
+		for _, m := range g.Members {
+			entry.Members = append(entry.Members, uidGroupMember{Name: m})
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return p.sendUIDEntries(ctx, "groups", entries)
+}
+
+// RegisteredIPs returns information about all of the IP addresses that have tags registered on the device.
+func (p *PaloAlto) RegisteredIPs() (*RegisteredIPs, error) {
+	return p.RegisteredIPsContext(context.Background())
+}
+
+// RegisteredIPsContext is the same as RegisteredIPs, but uses the given context for all API requests.
+func (p *PaloAlto) RegisteredIPsContext(ctx context.Context) (*RegisteredIPs, error) {
+	var parsedIPs xmlRegisteredIPs
+	var ips RegisteredIPs
+
+	query := map[string]string{
+		"type": "op",
+		"cmd":  "<show><object><registered-ip><all></all></registered-ip></object></show>",
+		"key":  p.Key,
+	}
+
+	ipData := p.send(ctx, "get", query)
+	if ipData.Error != nil {
+		return nil, ipData.Error
+	}
+
+	if err := xml.Unmarshal(ipData.Body, &parsedIPs); err != nil {
+		return nil, err
+	}
+
+	if parsedIPs.Status != "success" {
+		return nil, newAPIError(ipData, query)
+	}
+
+	for _, ip := range parsedIPs.IPs {
+		ips.IPs = append(ips.IPs, RegisteredIP{
+			IP:         ip.IP,
+			Tags:       trimLines(ip.Tags),
+			Persistent: strings.TrimSpace(ip.Persistent) == "1",
+			FromAgent:  strings.TrimSpace(ip.FromAgent) == "1",
+		})
+	}
+
+	return &ips, nil
+}
+
+// sendUIDEntries sends the given entries as one type of User-ID update (i.e. "login"), split up into as many
+// requests as needed to stay within userIDBatchSize and userIDMaxSize. Every batch is sent, even if the device
+// rejects some of the entries, and the failures are returned together as a *UserIDError.
+func (p *PaloAlto) sendUIDEntries(ctx context.Context, section string, entries []interface{}) error {
+	var failures []UserIDFailure
+	var batch [][]byte
+	size := 0
+
+	flush := func() error {
+		if len(batch) <= 0 {
+			return nil
+		}
+
+		failed, err := p.sendUIDMessage(ctx, section, batch)
+		if err != nil {
+			return err
+		}
+
+		failures = append(failures, failed...)
+		batch = nil
+		size = 0
+
+		return nil
+	}
+
+	for _, e := range entries {
+		entry, err := xml.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		if len(batch) >= userIDBatchSize || (len(batch) > 0 && size+len(entry) > userIDMaxSize) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		batch = append(batch, entry)
+		size += len(entry)
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if len(failures) > 0 {
+		return &UserIDError{Failures: failures}
+	}
+
+	return nil
+}
+
+// sendUIDMessage sends a single User-ID API request containing the given entries, and returns any entries that
+// the device rejected.
+func (p *PaloAlto) sendUIDMessage(ctx context.Context, section string, entries [][]byte) ([]UserIDFailure, error) {
+	var msg bytes.Buffer
+	var uidResp xmlUIDResponse
+	var failures []UserIDFailure
+
+	msg.WriteString("<uid-message><version>2.0</version><type>update</type><payload>")
+	msg.WriteString("<" + section + ">")
+	msg.Write(bytes.Join(entries, nil))
+	msg.WriteString("</" + section + "></payload></uid-message>")
+
+	query := map[string]string{
+		"type": "user-id",
+		"cmd":  msg.String(),
+		"key":  p.Key,
+	}
+
+	resp := p.send(ctx, "post", query)
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	if err := xml.Unmarshal(resp.Body, &uidResp); err != nil {
+		return nil, err
+	}
+
+	for _, s := range append(uidResp.Result.Sections, uidResp.Errors.Sections...) {
+		for _, e := range s.Entries {
+			failures = append(failures, UserIDFailure{
+				Type:    s.XMLName.Local,
+				Name:    e.Name,
+				IP:      e.IP,
+				Message: strings.TrimSpace(e.Message),
+			})
+		}
+	}
+
+	if uidResp.Status != "success" && len(failures) <= 0 {
+		return nil, newAPIError(resp, query)
+	}
+
+	return failures, nil
+}
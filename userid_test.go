@@ -0,0 +1,126 @@
+package panos
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRegisterIPTags(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	err := p.RegisterIPTags([]IPTagMapping{{IP: "10.1.1.1", Tags: []string{"web", "a&b"}, Timeout: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<uid-message><version>2.0</version><type>update</type><payload><register><entry ip="10.1.1.1"><tag>` +
+		`<member timeout="60">web</member><member timeout="60">a&amp;b</member></tag></entry></register></payload></uid-message>`
+
+	if cmd := d.sent("user-id")[0].Get("cmd"); cmd != want {
+		t.Errorf("got %s", cmd)
+	}
+
+	if err := p.UnregisterIPTags([]IPTagMapping{{IP: "10.1.1.1", Tags: []string{"web"}, Timeout: 60}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd := d.sent("user-id")[1].Get("cmd"); strings.Contains(cmd, "timeout") || !strings.Contains(cmd, "<unregister>") {
+		t.Errorf("got %s", cmd)
+	}
+}
+
+func TestRegisterIPTagsInvalid(t *testing.T) {
+	p, d := newFakeDevice(t, nil)
+
+	for _, m := range []IPTagMapping{{Tags: []string{"web"}}, {IP: "10.1.1.1"}, {IP: "10.1.1.1", Tags: []string{"web"}, Timeout: -1}} {
+		if err := p.RegisterIPTags([]IPTagMapping{m}); err == nil {
+			t.Errorf("expected an error for %+v", m)
+		}
+	}
+
+	if len(d.sent("")) != 0 {
+		t.Error("invalid mappings should not be sent")
+	}
+}
+
+func TestRegisteredIPs(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="success"><result count="1"><entry ip="10.1.1.1" from_agent="0" persistent="1">` +
+			`<tag><member>web</member><member> prod </member></tag></entry></result></response>`
+	})
+
+	ips, err := p.RegisteredIPs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ips.IPs) != 1 || ips.IPs[0].IP != "10.1.1.1" || !ips.IPs[0].Persistent || ips.IPs[0].FromAgent || ips.IPs[0].Tags[1] != "prod" {
+		t.Errorf("got %+v", ips.IPs)
+	}
+}
+
+func TestUserIDLoginFailures(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="error"><msg><line><uid-response><version>2.0</version><payload><login>` +
+			`<entry name="example\jdoe" ip="10.1.1.300" message=" invalid ip "/></login></payload></uid-response></line></msg></response>`
+	})
+
+	err := p.UserIDLogin([]UserMapping{{User: `example\jdoe`, IP: "10.1.1.300"}, {User: `example\ann`, IP: "10.1.1.2"}})
+
+	uidErr, ok := err.(*UserIDError)
+	if !ok {
+		t.Fatalf("err = %v, want a *UserIDError", err)
+	}
+
+	want := UserIDFailure{Type: "login", Name: `example\jdoe`, IP: "10.1.1.300", Message: "invalid ip"}
+	if len(uidErr.Failures) != 1 || uidErr.Failures[0] != want {
+		t.Errorf("got %+v", uidErr.Failures)
+	}
+
+	if msg := uidErr.Error(); msg != `1 User-ID entries failed: login example\jdoe 10.1.1.300: invalid ip` {
+		t.Errorf("got %q", msg)
+	}
+}
+
+func TestUserIDErrorResponse(t *testing.T) {
+	p, _ := newFakeDevice(t, func(q url.Values) string {
+		return `<response status="error" code="403"><result><msg>Invalid credentials.</msg></result></response>`
+	})
+
+	if err := p.UserIDLogout([]UserMapping{{User: "jdoe", IP: "10.1.1.1"}}); !IsUnauthorized(err) {
+		t.Errorf("err = %v, want an unauthorized error", err)
+	}
+}
+
+func TestUserIDBatches(t *testing.T) {
+	defer func(n, size int) { userIDBatchSize, userIDMaxSize = n, size }(userIDBatchSize, userIDMaxSize)
+	userIDBatchSize = 2
+
+	p, d := newFakeDevice(t, nil)
+
+	groups := []UserGroup{{Name: "a", Members: []string{"x"}}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+	if err := p.UserIDGroups(groups); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(d.sent("user-id")); n != 3 {
+		t.Errorf("sent %d requests, want 3", n)
+	}
+
+	// A single entry larger than the limit is still sent on its own.
+	userIDBatchSize = 500
+	userIDMaxSize = 10
+
+	if err := p.UserIDGroups(groups[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(d.sent("user-id")); n != 5 {
+		t.Errorf("sent %d requests, want 5", n)
+	}
+
+	if cmd := d.sent("user-id")[0].Get("cmd"); !strings.Contains(cmd, `<groups><entry name="a"><members><entry name="x"></entry></members></entry>`) {
+		t.Errorf("got %s", cmd)
+	}
+}